@@ -0,0 +1,54 @@
+package rtreego
+
+import (
+	"fmt"
+	"strings"
+)
+
+// dotEscaper escapes the characters of DOT quoted strings.
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// DOT returns a GraphViz DOT rendering of tree for debugging and teaching: a
+// directed graph with a box per node, labeled with its kind, its level and
+// its MBR, edges from each node to its children, and an ellipse per stored
+// object, labeled with its fmt %v formatting, linked from its leaf.  The
+// vertices are numbered in depth-first order, so the output is the same for
+// a given tree as long as the formatting of its objects is.
+func (tree *Rtree) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph rtree {\n")
+	b.WriteString("  node [shape=box];\n")
+	var nodes, objects int
+	writeDOTNode(&b, tree.root, &nodes, &objects)
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// writeDOTNode renders the subtree n into b, numbering its nodes and objects
+// from *nodes and *objects, and returns the name of the vertex of n.
+func writeDOTNode(b *strings.Builder, n *node, nodes, objects *int) string {
+	name := fmt.Sprintf("n%d", *nodes)
+	*nodes++
+	kind := "interior"
+	if n.leaf {
+		kind = "leaf"
+	}
+	mbr := "empty"
+	if len(n.entries) > 0 {
+		mbr = n.computeBoundingBox().String()
+	}
+	fmt.Fprintf(b, "  %s [label=\"%s, level %d\\n%s\"];\n", name, kind, n.level, mbr)
+
+	for _, e := range n.entries {
+		if n.leaf {
+			obj := fmt.Sprintf("o%d", *objects)
+			*objects++
+			fmt.Fprintf(b, "  %s [shape=ellipse, label=\"%s\"];\n", obj, dotEscaper.Replace(fmt.Sprint(e.obj)))
+			fmt.Fprintf(b, "  %s -> %s;\n", name, obj)
+			continue
+		}
+		child := writeDOTNode(b, e.child, nodes, objects)
+		fmt.Fprintf(b, "  %s -> %s;\n", name, child)
+	}
+	return name
+}
@@ -0,0 +1,58 @@
+package rtreego
+
+import (
+	"math/rand"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDOT(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 200)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			dot := rt.DOT()
+			if !strings.HasPrefix(dot, "digraph rtree {\n") || !strings.HasSuffix(dot, "}\n") {
+				t.Fatalf("DOT() isn't a digraph:\n%s", dot)
+			}
+
+			nodes, _, _ := rt.MemStats()
+			declared := regexp.MustCompile(`(?m)^  n\d+ \[label=`).FindAllString(dot, -1)
+			if len(declared) != nodes {
+				t.Errorf("DOT() declares %d nodes, expected %d", len(declared), nodes)
+			}
+			objects := regexp.MustCompile(`(?m)^  o\d+ \[shape=ellipse`).FindAllString(dot, -1)
+			if len(objects) != rt.Size() {
+				t.Errorf("DOT() declares %d objects, expected %d", len(objects), rt.Size())
+			}
+			// every vertex but the root has a single parent
+			edges := regexp.MustCompile(`(?m)^  n\d+ -> [no]\d+;$`).FindAllString(dot, -1)
+			if len(edges) != nodes-1+rt.Size() {
+				t.Errorf("DOT() has %d edges, expected %d", len(edges), nodes-1+rt.Size())
+			}
+
+			if again := rt.DOT(); again != dot {
+				t.Errorf("DOT() isn't deterministic")
+			}
+		})
+	}
+
+	if dot := NewTree(2, 3, 8).DOT(); !strings.Contains(dot, `n0 [label="leaf, level 1\nempty"];`) {
+		t.Errorf("DOT() of an empty tree = %s", dot)
+	}
+}
+
+// quoted is a Spatial whose formatting needs escaping in DOT.
+type quoted struct {
+	Rect
+}
+
+func (quoted) String() string { return "say \"hi\"\\" }
+
+func TestDOTEscape(t *testing.T) {
+	rt := NewTree(2, 3, 8, quoted{mustRect(Point{0, 0}, []float64{1, 1})})
+	if dot := rt.DOT(); !strings.Contains(dot, `label="say \"hi\"\\"`) {
+		t.Errorf("DOT() doesn't escape the labels of objects:\n%s", dot)
+	}
+}
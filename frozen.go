@@ -0,0 +1,146 @@
+package rtreego
+
+import "iter"
+
+// FrozenRtree is a read-only snapshot of an Rtree, returned by Freeze.  Its
+// structure can't be modified, so it can be queried from any number of
+// goroutines without locking.
+type FrozenRtree struct {
+	tree *Rtree
+}
+
+// Freeze returns a read-only snapshot of the current contents of tree.  The
+// snapshot is a Clone, so later changes to tree, such as Insert or Delete,
+// don't affect it, while the stored objects are shared by both.  The bounding
+// boxes of trees using LazyBounds are computed once by Freeze rather than on
+// every query.
+func (tree *Rtree) Freeze() *FrozenRtree {
+	clone := tree.Clone()
+	if clone.LazyBounds {
+		clone.LazyBounds = false
+		clone.storeBounds(clone.root)
+	}
+	return &FrozenRtree{tree: clone}
+}
+
+// storeBounds computes and stores the bounding boxes of the interior entries
+// of the subtree n.
+func (tree *Rtree) storeBounds(n *node) {
+	if n.leaf {
+		return
+	}
+	for i := range n.entries {
+		e := &n.entries[i]
+		tree.storeBounds(e.child)
+		e.bb = e.child.computeBoundingBox()
+	}
+}
+
+// Size returns the number of objects stored in the snapshot.
+func (ft *FrozenRtree) Size() int {
+	return ft.tree.Size()
+}
+
+// Depth returns the maximum depth of the snapshot.
+func (ft *FrozenRtree) Depth() int {
+	return ft.tree.Depth()
+}
+
+// String returns a multi-line rendering of the snapshot for debugging.
+func (ft *FrozenRtree) String() string {
+	return ft.tree.String()
+}
+
+// Bounds returns the smallest rectangle containing the bounds of every object
+// stored in the snapshot and whether it holds any objects.
+func (ft *FrozenRtree) Bounds() (Rect, bool) {
+	return ft.tree.Bounds()
+}
+
+// Contains reports whether obj is stored in the snapshot.
+func (ft *FrozenRtree) Contains(obj Spatial) bool {
+	return ft.tree.Contains(obj)
+}
+
+// SearchIntersect returns all objects that intersect the specified rectangle.
+func (ft *FrozenRtree) SearchIntersect(bb Rect, filters ...Filter) []Spatial {
+	return ft.tree.SearchIntersect(bb, filters...)
+}
+
+// SearchIntersectIter returns an iterator over the objects that intersect the
+// specified rectangle.
+func (ft *FrozenRtree) SearchIntersectIter(bb Rect, filters ...Filter) iter.Seq[Spatial] {
+	return ft.tree.SearchIntersectIter(bb, filters...)
+}
+
+// SearchIntersectBoxes returns the objects that intersect the specified
+// rectangle along with their stored bounding boxes.
+func (ft *FrozenRtree) SearchIntersectBoxes(bb Rect) []SearchResult {
+	return ft.tree.SearchIntersectBoxes(bb)
+}
+
+// Intersects reports whether any object intersects the specified rectangle.
+func (ft *FrozenRtree) Intersects(bb Rect, filters ...Filter) bool {
+	return ft.tree.Intersects(bb, filters...)
+}
+
+// ForEachInRect calls fn for each object that intersects the specified
+// rectangle, until fn returns false.
+func (ft *FrozenRtree) ForEachInRect(bb Rect, fn func(obj Spatial) bool) {
+	ft.tree.ForEachInRect(bb, fn)
+}
+
+// CountIntersect returns the number of objects that intersect the specified
+// rectangle.
+func (ft *FrozenRtree) CountIntersect(bb Rect) int {
+	return ft.tree.CountIntersect(bb)
+}
+
+// SearchContained returns all objects whose bounds are contained in the
+// specified rectangle.
+func (ft *FrozenRtree) SearchContained(bb Rect, filters ...Filter) []Spatial {
+	return ft.tree.SearchContained(bb, filters...)
+}
+
+// SearchContainingPoint returns all objects whose bounds contain p.
+func (ft *FrozenRtree) SearchContainingPoint(p Point, filters ...Filter) []Spatial {
+	return ft.tree.SearchContainingPoint(p, filters...)
+}
+
+// SearchWithinRadius returns all objects within radius of p.
+func (ft *FrozenRtree) SearchWithinRadius(p Point, radius float64, filters ...Filter) []Spatial {
+	return ft.tree.SearchWithinRadius(p, radius, filters...)
+}
+
+// NearestNeighbor returns the closest object to the specified point.
+func (ft *FrozenRtree) NearestNeighbor(p Point) Spatial {
+	return ft.tree.NearestNeighbor(p)
+}
+
+// ApproxNearest returns an object whose distance from p is at most 1+epsilon
+// times the distance of the closest object.
+func (ft *FrozenRtree) ApproxNearest(p Point, epsilon float64) Spatial {
+	return ft.tree.ApproxNearest(p, epsilon)
+}
+
+// NearestInRect returns the closest object to p among the objects that
+// intersect bb.
+func (ft *FrozenRtree) NearestInRect(p Point, bb Rect) Spatial {
+	return ft.tree.NearestInRect(p, bb)
+}
+
+// NearestNeighbors gets the k closest Spatials to the Point.
+func (ft *FrozenRtree) NearestNeighbors(k int, p Point, filters ...Filter) []Spatial {
+	return ft.tree.NearestNeighbors(k, p, filters...)
+}
+
+// ClosestPair returns the two distinct objects of the snapshot closest to each
+// other and their distance.
+func (ft *FrozenRtree) ClosestPair() (Spatial, Spatial, float64) {
+	return ft.tree.ClosestPair()
+}
+
+// GetAll returns all objects stored in the snapshot.
+func (ft *FrozenRtree) GetAll() []Spatial {
+	return ft.tree.GetAll()
+}
@@ -0,0 +1,60 @@
+package rtreego
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestFreezeConcurrent(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 2000)
+	for _, lazy := range []bool{false, true} {
+		rt := NewTree(2, 3, 8)
+		rt.LazyBounds = lazy
+		for _, thing := range things {
+			rt.Insert(thing)
+		}
+		ft := rt.Freeze()
+
+		// further changes to rt don't affect the snapshot
+		for _, thing := range randomRects(rnd, 100) {
+			rt.Insert(thing)
+		}
+		rt.Delete(things[0])
+		if ft.Size() != len(things) {
+			t.Errorf("Size() = %d after changing the original tree, expected %d", ft.Size(), len(things))
+		}
+		verify(t, ft.tree)
+
+		const readers, queries = 8, 200
+		var wg sync.WaitGroup
+		for r := 0; r < readers; r++ {
+			rnd := rand.New(rand.NewSource(int64(r)))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < queries; i++ {
+					p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+					bb := p.ToRect(5)
+					var expected []Spatial
+					for _, thing := range things {
+						if intersect(thing.Bounds(), bb) {
+							expected = append(expected, thing)
+						}
+					}
+					if q := ft.SearchIntersect(bb); len(q) != len(expected) {
+						t.Errorf("SearchIntersect(%v) returned %d objects with LazyBounds = %v, expected %d",
+							bb, len(q), lazy, len(expected))
+					}
+					if n := ft.CountIntersect(bb); n != len(expected) {
+						t.Errorf("CountIntersect(%v) = %d with LazyBounds = %v, expected %d", bb, n, lazy, len(expected))
+					}
+					ft.NearestNeighbors(3, p)
+					ft.SearchContained(bb)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,138 @@
+package rtreego
+
+import "math"
+
+// EarthRadius is the mean radius of the Earth in kilometers, used by
+// HaversineDistance and HaversineMinDist.
+const EarthRadius = 6371.0088
+
+// NearestNeighborFunc returns the object closest to p according to dist, or
+// nil if the tree is empty.  It generalizes NearestNeighbor to other metrics,
+// such as great-circle distances with HaversineMinDist.
+//
+// dist(p, bb) must return the distance from p to the closest point of bb, or
+// at least a lower bound of it: the bounding boxes of interior nodes are
+// pruned by comparing their distance to the distance of the best object found
+// so far, so a metric overestimating the distance to a box may miss the
+// nearest object.  Objects are ranked by the distance to their bounds.
+func (tree *Rtree) NearestNeighborFunc(p Point, dist func(p Point, bb Rect) float64) Spatial {
+	objs := tree.NearestNeighborsFunc(1, p, dist)
+	if len(objs) == 0 {
+		return nil
+	}
+	return objs[0]
+}
+
+// NearestNeighborsFunc gets the k closest Spatials to p according to dist,
+// sorted by increasing distance, like NearestNeighbors.  dist is subject to
+// the same requirements as for NearestNeighborFunc.
+func (tree *Rtree) NearestNeighborsFunc(k int, p Point, dist func(p Point, bb Rect) float64, filters ...Filter) []Spatial {
+	tree.checkDim(len(p))
+	return tree.kNearest(k, func(bb Rect) float64 { return dist(p, bb) }, filters)
+}
+
+// NearestNeighborWeighted returns the object closest to p, or nil if the tree
+// is empty, measuring distances with a weight per dimension, as
+// sqrt(sum(weights[i] * dx[i]^2)), which compares coordinates in different
+// units, such as meters and seconds.  Branches are pruned by the weighted
+// distance to their bounding boxes.  It panics with a DimError if weights
+// doesn't have the dimension of the tree, and with a DistError if some weight
+// is negative.
+func (tree *Rtree) NearestNeighborWeighted(p Point, weights []float64) Spatial {
+	tree.checkDim(len(weights))
+	for _, w := range weights {
+		if w < 0 || math.IsNaN(w) {
+			panic(DistError(w))
+		}
+	}
+	return tree.NearestNeighborFunc(p, func(p Point, bb Rect) float64 {
+		return weightedMinDist(p, bb, weights)
+	})
+}
+
+// weightedMinDist returns the squared distance from p to the closest point of
+// bb, weighting the squared difference along each dimension by weights.
+func weightedMinDist(p Point, bb Rect, weights []float64) float64 {
+	sum := 0.0
+	for i, x := range p {
+		var d float64
+		if x < bb.p[i] {
+			d = bb.p[i] - x
+		} else if x > bb.q[i] {
+			d = x - bb.q[i]
+		}
+		sum += weights[i] * d * d
+	}
+	return sum
+}
+
+// HaversineDistance returns the great-circle distance in kilometers between
+// a and b, which are given as {latitude, longitude} in degrees.
+func HaversineDistance(a, b Point) float64 {
+	if len(a) != 2 {
+		panic(DimError{2, len(a)})
+	}
+	if len(b) != 2 {
+		panic(DimError{2, len(b)})
+	}
+	return EarthRadius * haversine(a[0], a[1], b[0], b[1])
+}
+
+// HaversineMinDist returns the great-circle distance in kilometers from p to
+// the closest point of bb, where p and bb are given as {latitude, longitude}
+// in degrees.  The box is the region between its latitudes and between its
+// longitudes, while differences of longitude wrap around the antimeridian.
+// It is a valid metric for NearestNeighborFunc.
+func HaversineMinDist(p Point, bb Rect) float64 {
+	if len(p) != 2 {
+		panic(DimError{2, len(p)})
+	}
+	if len(bb.p) != 2 {
+		panic(DimError{2, len(bb.p)})
+	}
+	lat, lon := p[0], p[1]
+	lat1, lon1, lat2, lon2 := bb.p[0], bb.p[1], bb.q[0], bb.q[1]
+
+	// Within the longitudes of the box, the closest point lies on the same
+	// meridian as p.
+	if lon1 <= lon && lon <= lon2 {
+		return EarthRadius * haversine(lat, lon, math.Max(lat1, math.Min(lat, lat2)), lon)
+	}
+
+	// Otherwise it lies on the closest of the two meridian edges, since the
+	// distance to any latitude grows with the difference of longitudes.
+	edge := lon1
+	if lonDiff(lon, lon2) < lonDiff(lon, lon1) {
+		edge = lon2
+	}
+
+	// Along the edge, the distance is smallest at the latitude alpha and
+	// grows away from it, so it is smallest at alpha or at an end of the edge.
+	d := math.Min(haversine(lat, lon, lat1, edge), haversine(lat, lon, lat2, edge))
+	phi, dLambda := lat*math.Pi/180, (edge-lon)*math.Pi/180
+	alpha := math.Atan2(math.Sin(phi), math.Cos(phi)*math.Cos(dLambda)) * 180 / math.Pi
+	if lat1 <= alpha && alpha <= lat2 {
+		d = math.Min(d, haversine(lat, lon, alpha, edge))
+	}
+	return EarthRadius * d
+}
+
+// haversine returns the central angle in radians between two points given by
+// their latitudes and longitudes in degrees.
+func haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi, dLambda := phi2-phi1, (lon2-lon1)*math.Pi/180
+	h := math.Sin(dPhi/2)*math.Sin(dPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+// lonDiff returns the difference between two longitudes in degrees, wrapping
+// around the antimeridian, in [0, 180].
+func lonDiff(lon1, lon2 float64) float64 {
+	d := math.Mod(math.Abs(lon1-lon2), 360)
+	if d > 180 {
+		d = 360 - d
+	}
+	return d
+}
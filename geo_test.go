@@ -0,0 +1,141 @@
+package rtreego
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestHaversineDistance(t *testing.T) {
+	// a quarter of the equator
+	if d, expected := HaversineDistance(Point{0, 0}, Point{0, 90}), EarthRadius*math.Pi/2; math.Abs(d-expected) > EPS {
+		t.Errorf("HaversineDistance() = %v, expected %v", d, expected)
+	}
+	// across the antimeridian
+	if d, expected := HaversineDistance(Point{0, 179.5}, Point{0, -179.5}), EarthRadius*math.Pi/180; math.Abs(d-expected) > EPS {
+		t.Errorf("HaversineDistance() = %v, expected %v", d, expected)
+	}
+}
+
+func TestHaversineMinDist(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		lat1, lat2 := rnd.Float64()*180-90, rnd.Float64()*180-90
+		lon1, lon2 := rnd.Float64()*360-180, rnd.Float64()*360-180
+		bb, _ := NewRectFromPoints(Point{lat1, lon1}, Point{lat2, lon2})
+		p := Point{rnd.Float64()*180 - 90, rnd.Float64()*360 - 180}
+
+		// compare against the distance to a grid of points of the box
+		const steps = 100
+		closest := math.Inf(1)
+		for i := 0; i <= steps; i++ {
+			for j := 0; j <= steps; j++ {
+				q := Point{
+					bb.p[0] + bb.LengthsCoord(0)*float64(i)/steps,
+					bb.p[1] + bb.LengthsCoord(1)*float64(j)/steps,
+				}
+				closest = math.Min(closest, HaversineDistance(p, q))
+			}
+		}
+		d := HaversineMinDist(p, bb)
+		if d > closest+EPS || d < closest-200 {
+			t.Errorf("HaversineMinDist(%v, %v) = %v, closest point of the grid at %v", p, bb, d, closest)
+		}
+	}
+}
+
+func TestNearestNeighborFuncAntimeridian(t *testing.T) {
+	p := Point{10, 179.9}
+	across := Point{10, -179.9}
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i := 0; i < 300; i++ {
+		// points in the western hemisphere, far from the antimeridian
+		things = append(things, Point{rnd.Float64()*160 - 80, rnd.Float64()*170 - 170}.ToRect(0))
+	}
+	nearby := Point{10, 179}.ToRect(0)
+	things = append(things, &nearby, across.ToRect(0))
+
+	for _, tc := range tests(2, 3, 6, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			// the Euclidean distance ignores the wrap around
+			if nn := rt.NearestNeighbor(p); nn != Spatial(&nearby) {
+				t.Errorf("NearestNeighbor(%v) = %v, expected %v", p, nn, nearby)
+			}
+			if nn := rt.NearestNeighborFunc(p, HaversineMinDist); !nn.Bounds().Equal(across.ToRect(0)) {
+				t.Errorf("NearestNeighborFunc(%v) = %v, expected %v", p, nn, across)
+			}
+
+			// compare with a brute force search
+			for i := 0; i < 50; i++ {
+				q := Point{rnd.Float64()*160 - 80, rnd.Float64()*360 - 180}
+				nn := rt.NearestNeighborsFunc(3, q, HaversineMinDist)
+				var dists []float64
+				for _, thing := range things {
+					dists = append(dists, HaversineMinDist(q, thing.Bounds()))
+				}
+				sort.Float64s(dists)
+				for j, obj := range nn {
+					if d := HaversineMinDist(q, obj.Bounds()); d != dists[j] {
+						t.Errorf("neighbor %d of %v at distance %v, expected %v", j, q, d, dists[j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNearestNeighborWeighted(t *testing.T) {
+	// x and y in meters, z in seconds
+	near := mustRect(Point{10, 0, 0}, []float64{1, 1, 1})
+	soon := mustRect(Point{0, 0, 5}, []float64{1, 1, 1})
+	rt := NewTree(3, 2, 4, &near, &soon)
+	for i := 0; i < 50; i++ {
+		r := mustRect(Point{100 + float64(i), 100, 100}, []float64{1, 1, 1})
+		rt.Insert(&r)
+	}
+
+	p := Point{0, 0, 0}
+	if obj := rt.NearestNeighborWeighted(p, []float64{1, 1, 1}); obj != &soon {
+		t.Errorf("NearestNeighborWeighted(%v) with equal weights = %v, expected %v", p, obj, &soon)
+	}
+	// a second weighs as much as 100 meters
+	if obj := rt.NearestNeighborWeighted(p, []float64{1, 1, 100 * 100}); obj != &near {
+		t.Errorf("NearestNeighborWeighted(%v) weighting time = %v, expected %v", p, obj, &near)
+	}
+	if obj := NewTree(3, 2, 4).NearestNeighborWeighted(p, []float64{1, 1, 1}); obj != nil {
+		t.Errorf("NearestNeighborWeighted on an empty tree = %v, expected nil", obj)
+	}
+
+	// the weighted pruning finds the same distance as a linear scan
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		q := Point{rnd.Float64() * 150, rnd.Float64() * 150, rnd.Float64() * 150}
+		weights := []float64{rnd.Float64(), rnd.Float64() * 10, rnd.Float64() * 100}
+		expected := math.Inf(1)
+		for _, obj := range rt.GetAll() {
+			expected = math.Min(expected, weightedMinDist(q, obj.Bounds(), weights))
+		}
+		obj := rt.NearestNeighborWeighted(q, weights)
+		if d := weightedMinDist(q, obj.Bounds(), weights); d != expected {
+			t.Errorf("NearestNeighborWeighted(%v, %v) returned an object at %v, expected %v", q, weights, d, expected)
+		}
+	}
+
+	for name, weights := range map[string][]float64{
+		"dimension": {1, 1},
+		"negative":  {1, -1, 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NearestNeighborWeighted(%v, %v) didn't panic", p, weights)
+				}
+			}()
+			rt.NearestNeighborWeighted(p, weights)
+		})
+	}
+}
@@ -7,25 +7,30 @@ package rtreego
 import (
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
+	"sync"
 )
 
-// DimError represents a failure due to mismatched dimensions.
+// DimError represents a failure due to mismatched dimensions.  Functions
+// returning errors return a *DimError, which can be detected with errors.As,
+// while functions that can't fail otherwise panic with a DimError.
 type DimError struct {
 	Expected int
 	Actual   int
 }
 
 func (err DimError) Error() string {
-	return "rtreego: dimension mismatch"
+	return fmt.Sprintf("rtreego: dimension mismatch: expected %d, got %d", err.Expected, err.Actual)
 }
 
 // DistError is an improper distance measurement.  It implements the error
-// and is generated when a distance-related assertion fails.
+// and is generated when a distance-related assertion fails, such as a
+// negative length or tolerance.
 type DistError float64
 
 func (err DistError) Error() string {
-	return "rtreego: improper distance"
+	return fmt.Sprintf("rtreego: improper distance %v", float64(err))
 }
 
 // Point represents a point in n-dimensional Euclidean space.
@@ -50,14 +55,16 @@ func (p Point) dist(q Point) float64 {
 	return math.Sqrt(sum)
 }
 
-// minDist computes the square of the distance from a point to a rectangle.
-// If the point is contained in the rectangle then the distance is zero.
+// MinDist computes the square of the Euclidean distance from p to the closest
+// point of r, which is zero if p is contained in r.  The distance is left
+// squared, which preserves the order of distances without computing a square
+// root.  It panics with a DimError if p doesn't have the dimension of r.
 //
 // Implemented per Definition 2 of "Nearest Neighbor Queries" by
 // N. Roussopoulos, S. Kelley and F. Vincent, ACM SIGMOD, pages 71-79, 1995.
-func (p Point) minDist(r Rect) float64 {
+func (r Rect) MinDist(p Point) float64 {
 	if len(p) != len(r.p) {
-		panic(DimError{len(p), len(r.p)})
+		panic(DimError{len(r.p), len(p)})
 	}
 
 	sum := 0.0
@@ -68,8 +75,38 @@ func (p Point) minDist(r Rect) float64 {
 		} else if pi > r.q[i] {
 			d := pi - r.q[i]
 			sum += d * d
-		} else {
-			sum += 0
+		}
+	}
+	return sum
+}
+
+// minDist is r.MinDist(p), whose method value p.minDist measures distances
+// from p.
+func (p Point) minDist(r Rect) float64 {
+	return r.MinDist(p)
+}
+
+// DistTo computes the Euclidean distance between r and other, i.e. the length
+// of the shortest segment joining a point of r to a point of other.  The
+// distance is zero if the rectangles intersect.
+func (r Rect) DistTo(other Rect) float64 {
+	return math.Sqrt(r.minDistRect(other))
+}
+
+// minDistRect computes the square of the distance between two rectangles.
+func (r Rect) minDistRect(other Rect) float64 {
+	if len(r.p) != len(other.p) {
+		panic(DimError{len(r.p), len(other.p)})
+	}
+
+	sum := 0.0
+	for i := range r.p {
+		if other.q[i] < r.p[i] {
+			d := r.p[i] - other.q[i]
+			sum += d * d
+		} else if other.p[i] > r.q[i] {
+			d := other.p[i] - r.q[i]
+			sum += d * d
 		}
 	}
 	return sum
@@ -128,11 +165,20 @@ func (p Point) minMaxDist(r Rect) float64 {
 }
 
 // Rect represents a subset of n-dimensional Euclidean space of the form
-// [a1, b1] x [a2, b2] x ... x [an, bn], where ai < bi for all 1 <= i <= n.
+// [a1, b1] x [a2, b2] x ... x [an, bn], where ai <= bi for all 1 <= i <= n.
 type Rect struct {
 	p, q Point // Enforced by NewRect: p[i] <= q[i] for all i.
 }
 
+// clone returns a copy of r that doesn't share its coordinates.  The zero
+// Rect is returned as is.
+func (r Rect) clone() Rect {
+	if r.p == nil {
+		return r
+	}
+	return Rect{r.p.Copy(), r.q.Copy()}
+}
+
 // PointCoord returns the coordinate of the point of the rectangle at i
 func (r Rect) PointCoord(i int) float64 {
 	return r.p[i]
@@ -143,8 +189,39 @@ func (r Rect) LengthsCoord(i int) float64 {
 	return r.q[i] - r.p[i]
 }
 
-// Equal returns true if the two rectangles are equal
+// MinCorner returns the corner of the rectangle with the smallest coordinates,
+// which is also its point.  The result is a copy that can be modified freely.
+func (r Rect) MinCorner() Point {
+	return r.p.Copy()
+}
+
+// MaxCorner returns the corner of the rectangle with the largest coordinates,
+// its point plus its lengths.  The result is a copy that can be modified
+// freely.  NewRectFromPoints(r.MinCorner(), r.MaxCorner()) is equal to r.
+func (r Rect) MaxCorner() Point {
+	return r.q.Copy()
+}
+
+// Floats returns a compact form of the rectangle for serialization: the
+// coordinates of its point followed by its lengths, 2*d floats for a
+// rectangle of dimension d, which RectFromFloats turns back into a Rect.  As
+// with NewRect, the upper corner is recomputed by adding the lengths, which
+// may round its coordinates.
+func (r Rect) Floats() []float64 {
+	f := make([]float64, 2*len(r.p))
+	copy(f, r.p)
+	for i := range r.p {
+		f[len(r.p)+i] = r.q[i] - r.p[i]
+	}
+	return f
+}
+
+// Equal returns true if the two rectangles are equal: they have the same
+// dimension and exactly the same coordinates.
 func (r Rect) Equal(other Rect) bool {
+	if len(r.p) != len(other.p) {
+		return false
+	}
 	for i, e := range r.p {
 		if e != other.p[i] {
 			return false
@@ -158,18 +235,25 @@ func (r Rect) Equal(other Rect) bool {
 	return true
 }
 
+// String returns the extent of r in each dimension, such as [0,1]x[2,3.5] in
+// two dimensions.  Coordinates are printed with the fewest digits that
+// represent them exactly, so they can be parsed back with strconv.ParseFloat.
 func (r Rect) String() string {
 	s := make([]string, len(r.p))
 	for i, a := range r.p {
 		b := r.q[i]
-		s[i] = fmt.Sprintf("[%.2f, %.2f]", a, b)
+		s[i] = "[" + strconv.FormatFloat(a, 'g', -1, 64) + "," + strconv.FormatFloat(b, 'g', -1, 64) + "]"
 	}
 	return strings.Join(s, "x")
 }
 
-// NewRect constructs and returns a pointer to a Rect given a corner point and
-// the lengths of each dimension.  The point p should be the most-negative point
-// on the rectangle (in every dimension) and every length should be positive.
+// NewRect constructs and returns a Rect given a corner point and the lengths
+// of each dimension.  The point p should be the most-negative point on the
+// rectangle (in every dimension) and every length should be non-negative.  A
+// zero length yields a degenerate rectangle, such as a point or a segment.
+// NewRect returns a *DimError if p and lengths have different dimensions, a
+// DistError if some length is negative, and an error if some coordinate of
+// the rectangle is NaN or infinite.
 func NewRect(p Point, lengths []float64) (r Rect, err error) {
 	r.p = p
 	if len(p) != len(lengths) {
@@ -178,16 +262,21 @@ func NewRect(p Point, lengths []float64) (r Rect, err error) {
 	}
 	r.q = make([]float64, len(p))
 	for i := range p {
-		if lengths[i] <= 0 {
+		if lengths[i] < 0 {
 			err = DistError(lengths[i])
 			return
 		}
 		r.q[i] = p[i] + lengths[i]
 	}
+	err = r.checkFinite()
 	return
 }
 
-// NewRectFromPoints constructs and returns a pointer to a Rect given a corner points.
+// NewRectFromPoints constructs and returns a Rect given two corner points.
+// The coordinates are reordered as needed, so minPoint and maxPoint may be any
+// two opposite corners.  NewRectFromPoints returns a *DimError if the points
+// have different dimensions, and an error if some coordinate is NaN or
+// infinite.
 func NewRectFromPoints(minPoint, maxPoint Point) (r Rect, err error) {
 	if len(minPoint) != len(maxPoint) {
 		err = &DimError{len(minPoint), len(maxPoint)}
@@ -209,9 +298,36 @@ func NewRectFromPoints(minPoint, maxPoint Point) (r Rect, err error) {
 	}
 
 	r = Rect{p: minPoint, q: maxPoint}
+	err = r.checkFinite()
 	return
 }
 
+// RectFromFloats constructs a Rect from the compact form returned by
+// Rect.Floats: the coordinates of its point followed by its lengths.  It
+// returns an error if f is empty or has an odd length, and otherwise the
+// errors of NewRect, such as a DistError if some length is negative.  The
+// coordinates are copied, so f can be reused.
+func RectFromFloats(f []float64) (Rect, error) {
+	if len(f) == 0 || len(f)%2 != 0 {
+		return Rect{}, fmt.Errorf("rtreego: invalid rectangle of %d floats, expected a positive even number", len(f))
+	}
+	dim := len(f) / 2
+	return NewRect(Point(f[:dim]).Copy(), f[dim:])
+}
+
+// checkFinite returns an error if some coordinate of r is NaN or infinite,
+// which would break the comparisons of bounding boxes made by the tree.
+func (r Rect) checkFinite() error {
+	for _, pts := range []Point{r.p, r.q} {
+		for i, x := range pts {
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				return fmt.Errorf("rtreego: invalid coordinate %v in dimension %d of %v", x, i, r)
+			}
+		}
+	}
+	return nil
+}
+
 // Size computes the measure of a rectangle (the product of its side lengths).
 func (r Rect) Size() float64 {
 	size := 1.0
@@ -222,8 +338,25 @@ func (r Rect) Size() float64 {
 	return size
 }
 
-// margin computes the sum of the edge lengths of a rectangle.
-func (r Rect) margin() float64 {
+// Area computes the measure of a rectangle like Size: its length in one
+// dimension, its area in two and its volume in three or more.
+func (r Rect) Area() float64 {
+	return r.Size()
+}
+
+// Center computes the center point of a rectangle, the midpoint of its
+// extent in each dimension.
+func (r Rect) Center() Point {
+	c := make(Point, len(r.p))
+	for i, a := range r.p {
+		c[i] = (a + r.q[i]) / 2
+	}
+	return c
+}
+
+// Margin computes the sum of the edge lengths of a rectangle, which
+// generalizes the perimeter to n dimensions.
+func (r Rect) Margin() float64 {
 	// The number of edges in an n-dimensional rectangle is n * 2^(n-1)
 	// (http://en.wikipedia.org/wiki/Hypercube_graph).  Thus the number
 	// of edges of length (ai - bi), where the rectangle is determined
@@ -240,8 +373,9 @@ func (r Rect) margin() float64 {
 	return math.Pow(2, float64(dim-1)) * sum
 }
 
-// containsPoint tests whether p is located inside or on the boundary of r.
-func (r Rect) containsPoint(p Point) bool {
+// ContainsPoint tests whether p is located inside or on the boundary of r.  It
+// panics with a DimError if p and r have different dimensions.
+func (r Rect) ContainsPoint(p Point) bool {
 	if len(p) != len(r.p) {
 		panic(DimError{len(r.p), len(p)})
 	}
@@ -257,8 +391,10 @@ func (r Rect) containsPoint(p Point) bool {
 	return true
 }
 
-// containsRect tests whether r2 is is located inside r1.
-func (r Rect) containsRect(r2 Rect) bool {
+// ContainsRect tests whether r2 is located inside r, possibly touching its
+// boundary, so that every rectangle contains itself.  It panics with a
+// DimError if r and r2 have different dimensions.
+func (r Rect) ContainsRect(r2 Rect) bool {
 	if len(r.p) != len(r2.p) {
 		panic(DimError{len(r.p), len(r2.p)})
 	}
@@ -276,8 +412,78 @@ func (r Rect) containsRect(r2 Rect) bool {
 	return true
 }
 
-// intersect computes the intersection of two rectangles.  If no intersection
-// exists, the intersection is nil.
+// Expand returns a copy of r enlarged by margin on every side, so that each
+// length grows by twice margin.  A negative margin shrinks r instead, down to
+// a zero length at the center of r along the dimensions that are too short.
+// r itself is unchanged.
+func (r Rect) Expand(margin float64) Rect {
+	result := Rect{make(Point, len(r.p)), make(Point, len(r.p))}
+	for i := range r.p {
+		a, b := r.p[i]-margin, r.q[i]+margin
+		if a > b {
+			a = (r.p[i] + r.q[i]) / 2
+			b = a
+		}
+		result.p[i], result.q[i] = a, b
+	}
+	return result
+}
+
+// Translate returns a copy of r moved by delta along every dimension.  It
+// panics with a DimError if delta doesn't have the dimension of r.
+func (r Rect) Translate(delta Point) Rect {
+	if len(delta) != len(r.p) {
+		panic(DimError{len(r.p), len(delta)})
+	}
+	result := Rect{make(Point, len(r.p)), make(Point, len(r.p))}
+	for i := range r.p {
+		result.p[i], result.q[i] = r.p[i]+delta[i], r.q[i]+delta[i]
+	}
+	return result
+}
+
+// Scale returns a copy of r whose lengths are multiplied by factor, keeping
+// the lower corner of r in place: the scaling is about the corner, not the
+// center.  A zero factor yields the lower corner itself.  It panics with a
+// DistError if factor is negative.
+func (r Rect) Scale(factor float64) Rect {
+	if factor < 0 {
+		panic(DistError(factor))
+	}
+	result := Rect{make(Point, len(r.p)), make(Point, len(r.p))}
+	for i := range r.p {
+		result.p[i], result.q[i] = r.p[i], r.p[i]+(r.q[i]-r.p[i])*factor
+	}
+	return result
+}
+
+// Intersects tests whether r and other intersect, including when they merely
+// touch on an edge or a corner.  It panics with a DimError if the rectangles
+// have different dimensions, which is a programming error.
+func (r Rect) Intersects(other Rect) bool {
+	return intersect(r, other)
+}
+
+// Intersection returns the rectangle where r and other overlap and whether
+// they overlap at all; if they are disjoint, it returns the zero Rect and
+// false.  Rectangles that merely touch on a face, an edge or a corner have a
+// degenerate intersection with zero lengths along the dimensions where they
+// touch, which is returned with true.  It panics with a DimError if the
+// rectangles have different dimensions.
+func (r Rect) Intersection(other Rect) (Rect, bool) {
+	if !intersect(r, other) {
+		return Rect{}, false
+	}
+	result := Rect{make(Point, len(r.p)), make(Point, len(r.p))}
+	for i := range r.p {
+		result.p[i] = math.Max(r.p[i], other.p[i])
+		result.q[i] = math.Min(r.q[i], other.q[i])
+	}
+	return result, true
+}
+
+// intersect tests whether two rectangles intersect.  Rectangles are closed,
+// so rectangles that merely touch on an edge or a corner intersect.
 func intersect(r1, r2 Rect) bool {
 	dim := len(r1.p)
 	if len(r2.p) != dim {
@@ -311,19 +517,95 @@ func intersect(r1, r2 Rect) bool {
 	//        a2------b2
 	//
 	// Enforced by constructor: a1 <= b1 and a2 <= b2.  So we can just
-	// check the endpoints.
+	// check the endpoints.  Touching endpoints (b1 == a2 or b2 == a1)
+	// count as overlap.
 
 	for i := range r1.p {
 		a1, b1, a2, b2 := r1.p[i], r1.q[i], r2.p[i], r2.q[i]
-		if b2 <= a1 || b1 <= a2 {
+		if b2 < a1 || b1 < a2 {
 			return false
 		}
 	}
 	return true
 }
 
-// ToRect constructs a rectangle containing p with side lengths 2*tol.
+// intersectHalfOpen tests whether two rectangles intersect when they exclude
+// their upper bounds, so that rectangles touching on an edge or a corner don't
+// intersect.  Along the dimensions where a rectangle has a zero length, it is
+// the single coordinate of its lower bound.
+func intersectHalfOpen(r1, r2 Rect) bool {
+	dim := len(r1.p)
+	if len(r2.p) != dim {
+		panic(DimError{dim, len(r2.p)})
+	}
+
+	for i := range r1.p {
+		a1, b1, a2, b2 := r1.p[i], r1.q[i], r2.p[i], r2.q[i]
+		var overlaps bool
+		switch {
+		case a1 == b1 && a2 == b2:
+			overlaps = a1 == a2
+		case a1 == b1:
+			overlaps = a2 <= a1 && a1 < b2
+		case a2 == b2:
+			overlaps = a1 <= a2 && a2 < b1
+		default:
+			overlaps = a1 < b2 && a2 < b1
+		}
+		if !overlaps {
+			return false
+		}
+	}
+	return true
+}
+
+// overlap computes the measure of the intersection of two rectangles, which
+// is zero if they don't intersect.
+func overlap(r1, r2 Rect) float64 {
+	dim := len(r1.p)
+	if len(r2.p) != dim {
+		panic(DimError{dim, len(r2.p)})
+	}
+
+	size := 1.0
+	for i := range r1.p {
+		lo := math.Max(r1.p[i], r2.p[i])
+		hi := math.Min(r1.q[i], r2.q[i])
+		if hi <= lo {
+			return 0
+		}
+		size *= hi - lo
+	}
+	return size
+}
+
+// compareRects defines a total order on rectangles of the same dimension by
+// comparing the coordinates of their lower corners and then those of their
+// upper corners.  It returns -1, 0 or 1 if r1 orders before, equal to or
+// after r2.
+func compareRects(r1, r2 Rect) int {
+	for _, pts := range [][2]Point{{r1.p, r2.p}, {r1.q, r2.q}} {
+		for i := range pts[0] {
+			if a, b := pts[0][i], pts[1][i]; a < b {
+				return -1
+			} else if a > b {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// ToRect constructs a rectangle centered at p with side lengths 2*tol, that
+// is [p1-tol, p1+tol] x ... x [pn-tol, pn+tol].  The rectangle has as many
+// dimensions as p, which must match the Dim of any tree it is used with.  A
+// zero tol yields a degenerate rectangle containing only p itself, which is
+// how points are stored in a tree.  ToRect panics with a DistError if tol is
+// negative.
 func (p Point) ToRect(tol float64) Rect {
+	if tol < 0 {
+		panic(DistError(tol))
+	}
 	dim := len(p)
 	a, b := make([]float64, dim), make([]float64, dim)
 	for i := range p {
@@ -333,25 +615,72 @@ func (p Point) ToRect(tol float64) Rect {
 	return Rect{a, b}
 }
 
-// boundingBox constructs the smallest rectangle containing both r1 and r2.
-func boundingBox(r1, r2 Rect) (bb Rect) {
+// boundingBoxOf constructs the smallest rectangle containing the bounds of
+// all objs, or the zero Rect if objs is empty.
+func boundingBoxOf(objs []Spatial) (bb Rect) {
+	if len(objs) == 0 {
+		return
+	}
+	bb = objs[0].Bounds()
+	for _, obj := range objs[1:] {
+		bb = bb.Union(obj.Bounds())
+	}
+	return
+}
+
+// Union returns the smallest rectangle containing both r and other.  It
+// panics with a DimError if the rectangles have different dimensions, which
+// is a programming error.
+func (r Rect) Union(other Rect) Rect {
+	dim := len(r.p)
+	if len(other.p) != dim {
+		panic(DimError{dim, len(other.p)})
+	}
+	bb := Rect{make(Point, dim), make(Point, dim)}
+	unionInto(bb, r, other)
+	return bb
+}
+
+// unionInto stores the smallest rectangle containing r1 and r2 in the
+// coordinates of dst, which may be r1 or r2, without allocating.
+func unionInto(dst, r1, r2 Rect) {
 	dim := len(r1.p)
-	bb.p = make([]float64, dim)
-	bb.q = make([]float64, dim)
 	if len(r2.p) != dim {
 		panic(DimError{dim, len(r2.p)})
 	}
 	for i := 0; i < dim; i++ {
 		if r1.p[i] <= r2.p[i] {
-			bb.p[i] = r1.p[i]
+			dst.p[i] = r1.p[i]
 		} else {
-			bb.p[i] = r2.p[i]
+			dst.p[i] = r2.p[i]
 		}
 		if r1.q[i] <= r2.q[i] {
-			bb.q[i] = r2.q[i]
+			dst.q[i] = r2.q[i]
 		} else {
-			bb.q[i] = r1.q[i]
+			dst.q[i] = r1.q[i]
 		}
 	}
-	return
+}
+
+// rectPool holds the scratch rectangles used for the bounding boxes compared
+// while choosing nodes and splitting them, which are discarded right away.
+// Pooled rectangles must never be stored in entries.
+var rectPool = sync.Pool{New: func() any { return new(Rect) }}
+
+// scratchRect returns a rectangle of dimension dim from rectPool, with
+// unspecified coordinates, which must be given back with releaseRect.
+func scratchRect(dim int) *Rect {
+	r := rectPool.Get().(*Rect)
+	if cap(r.p) < dim {
+		r.p, r.q = make(Point, dim), make(Point, dim)
+	}
+	r.p, r.q = r.p[:dim], r.q[:dim]
+	return r
+}
+
+// releaseRect gives the scratch rectangles back to rectPool.
+func releaseRect(rs ...*Rect) {
+	for _, r := range rs {
+		rectPool.Put(r)
+	}
 }
@@ -5,7 +5,10 @@
 package rtreego
 
 import (
+	"errors"
 	"math"
+	"math/rand"
+	"slices"
 	"testing"
 )
 
@@ -53,6 +56,79 @@ func TestNewRectFromPoints(t *testing.T) {
 	}
 }
 
+func TestRectCorners(t *testing.T) {
+	p := Point{1.0, -2.5, 3.0}
+	lengths := []float64{2.5, 8.0, 1.5}
+	rect := mustRect(p, lengths)
+
+	min, max := rect.MinCorner(), rect.MaxCorner()
+	for i := range p {
+		if min[i] != p[i] {
+			t.Errorf("MinCorner()[%d] = %v, expected %v", i, min[i], p[i])
+		}
+		if d := max[i] - (min[i] + rect.LengthsCoord(i)); math.Abs(d) > EPS {
+			t.Errorf("MaxCorner()[%d] = %v, expected MinCorner + lengths = %v", i, max[i], min[i]+rect.LengthsCoord(i))
+		}
+	}
+
+	same, err := NewRectFromPoints(min, max)
+	if err != nil {
+		t.Fatalf("NewRectFromPoints(%v, %v) = %v", min, max, err)
+	}
+	if !same.Equal(rect) {
+		t.Errorf("NewRectFromPoints(MinCorner(), MaxCorner()) = %v, expected %v", same, rect)
+	}
+
+	// the corners are copies
+	min[0], max[0] = 100, 200
+	if rect.PointCoord(0) != 1.0 || rect.LengthsCoord(0) != 2.5 {
+		t.Errorf("modifying the corners changed the rectangle to %v", rect)
+	}
+}
+
+func TestRectFloats(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for dim := 1; dim <= 5; dim++ {
+		p := make(Point, dim)
+		lengths := make([]float64, dim)
+		for i := range p {
+			// multiples of 1/8 add up exactly
+			p[i] = float64(rnd.Intn(200)-100) / 8
+			lengths[i] = float64(rnd.Intn(80)) / 8
+		}
+		rect := mustRect(p, lengths)
+
+		f := rect.Floats()
+		if len(f) != 2*dim {
+			t.Fatalf("Floats() of %v has %d floats, expected %d", rect, len(f), 2*dim)
+		}
+		for i := range p {
+			if f[i] != p[i] || f[dim+i] != lengths[i] {
+				t.Errorf("Floats() of %v = %v, expected %v followed by %v", rect, f, p, lengths)
+				break
+			}
+		}
+
+		back, err := RectFromFloats(f)
+		if err != nil {
+			t.Fatalf("RectFromFloats(%v) = %v", f, err)
+		}
+		if !back.Equal(rect) {
+			t.Errorf("RectFromFloats(%v) = %v, expected %v", f, back, rect)
+		}
+		f[0] = 1000
+		if back.PointCoord(0) == 1000 {
+			t.Errorf("RectFromFloats shares its coordinates with its argument")
+		}
+	}
+
+	for _, f := range [][]float64{nil, {1, 2, 3}, {0, 0, 1, -1}, {math.NaN(), 1}} {
+		if _, err := RectFromFloats(f); err == nil {
+			t.Errorf("RectFromFloats(%v) = nil, expected an error", f)
+		}
+	}
+}
+
 func TestNewRectFromPointsWithSwapPoints(t *testing.T) {
 	p := Point{1.0, -2.5, 3.0}
 	q := Point{3.5, 5.5, 4.5}
@@ -79,6 +155,32 @@ func TestNewRectDimMismatch(t *testing.T) {
 	}
 }
 
+func TestErrorTypes(t *testing.T) {
+	_, err := NewRect(Point{0, 0}, []float64{1, 1, 1})
+	var dimErr *DimError
+	if !errors.As(err, &dimErr) || dimErr.Expected != 2 || dimErr.Actual != 3 {
+		t.Errorf("NewRect() = %v, expected a *DimError", err)
+	}
+
+	_, err = NewRect(Point{0, 0}, []float64{1, -2})
+	var distErr DistError
+	if !errors.As(err, &distErr) || distErr != -2 {
+		t.Errorf("NewRect() = %v, expected a DistError", err)
+	}
+
+	err = NewTree(2, 3, 8).Insert(mustRect(Point{0}, []float64{1}))
+	if !errors.As(err, &dimErr) || dimErr.Expected != 2 || dimErr.Actual != 1 {
+		t.Errorf("Insert() = %v, expected a *DimError", err)
+	}
+
+	if msg := (DimError{2, 3}).Error(); msg != "rtreego: dimension mismatch: expected 2, got 3" {
+		t.Errorf("DimError.Error() = %q", msg)
+	}
+	if msg := DistError(-2).Error(); msg != "rtreego: improper distance -2" {
+		t.Errorf("DistError.Error() = %q", msg)
+	}
+}
+
 func TestNewRectDistError(t *testing.T) {
 	p := Point{1.0, -2.5, 3.0}
 	lengths := []float64{2.5, -8.0, 1.5}
@@ -88,6 +190,64 @@ func TestNewRectDistError(t *testing.T) {
 	}
 }
 
+func TestNewRectNonFinite(t *testing.T) {
+	for _, tt := range []struct {
+		p       Point
+		lengths []float64
+	}{
+		{Point{math.NaN(), 0}, []float64{1, 1}},
+		{Point{0, 0}, []float64{1, math.NaN()}},
+		{Point{math.Inf(-1), 0}, []float64{1, 1}},
+		{Point{0, 0}, []float64{math.Inf(1), 1}},
+		{Point{math.MaxFloat64, 0}, []float64{math.MaxFloat64, 1}},
+	} {
+		if _, err := NewRect(tt.p, tt.lengths); err == nil {
+			t.Errorf("NewRect(%v, %v) = nil, expected an error", tt.p, tt.lengths)
+		}
+	}
+	if _, err := NewRectFromPoints(Point{0, math.NaN()}, Point{1, 1}); err == nil {
+		t.Errorf("NewRectFromPoints() = nil with a NaN coordinate")
+	}
+	if _, err := NewRectFromPoints(Point{0, 0}, Point{math.Inf(1), 1}); err == nil {
+		t.Errorf("NewRectFromPoints() = nil with an infinite coordinate")
+	}
+}
+
+func TestNewRectZeroLength(t *testing.T) {
+	p := Point{1.0, -2.5, 3.0}
+	lengths := []float64{2.5, 0, 1.5}
+	rect, err := NewRect(p, lengths)
+	if err != nil {
+		t.Fatalf("Error on NewRect(%v, %v): %v", p, lengths, err)
+	}
+	if s := rect.Size(); s != 0 {
+		t.Errorf("Expected NewRect(%v, %v).Size() == 0, got %v", p, lengths, s)
+	}
+	if !rect.ContainsPoint(Point{2.0, -2.5, 4.0}) {
+		t.Errorf("Expected %v to contain a point on its degenerate side", rect)
+	}
+}
+
+func TestNewRectFromPointsDimMismatch(t *testing.T) {
+	p := Point{-7.0, 10.0}
+	q := Point{2.5, 8.0, 1.5}
+	_, err := NewRectFromPoints(p, q)
+	if _, ok := err.(*DimError); !ok {
+		t.Errorf("Expected DimError on NewRectFromPoints(%v, %v)", p, q)
+	}
+}
+
+func TestNewRectFromPointsSamePoint(t *testing.T) {
+	p := Point{1.0, -2.5, 3.0}
+	rect, err := NewRectFromPoints(p, p)
+	if err != nil {
+		t.Fatalf("Error on NewRectFromPoints(%v, %v): %v", p, p, err)
+	}
+	if s := rect.Size(); s != 0 {
+		t.Errorf("Expected NewRectFromPoints(%v, %v).Size() == 0, got %v", p, p, s)
+	}
+}
+
 func TestRectPointCoord(t *testing.T) {
 	p := Point{1.0, -2.5}
 	lengths := []float64{2.5, 8.0}
@@ -130,6 +290,34 @@ func TestRectEqual(t *testing.T) {
 	if a.Equal(c) {
 		t.Errorf("Expected %v.Equal(%v) to return false", a, c)
 	}
+
+	// rectangles of different dimensions are never equal
+	flat := mustRect(Point{1.0, -2.5}, []float64{2.5, 8.0})
+	if a.Equal(flat) || flat.Equal(a) {
+		t.Errorf("Expected %v and %v not to be equal", a, flat)
+	}
+	if !flat.Equal(mustRect(Point{1.0, -2.5}, []float64{2.5, 8.0})) {
+		t.Errorf("Expected %v to equal itself", flat)
+	}
+	var empty Rect
+	if !empty.Equal(Rect{}) || empty.Equal(flat) {
+		t.Errorf("Expected the zero Rect to equal only itself")
+	}
+}
+
+func TestRectString(t *testing.T) {
+	for _, tt := range []struct {
+		r        Rect
+		expected string
+	}{
+		{mustRect(Point{0}, []float64{1}), "[0,1]"},
+		{mustRect(Point{1.0, -2.5}, []float64{2.5, 8.0}), "[1,3.5]x[-2.5,5.5]"},
+		{mustRect(Point{0.1, 0, -3}, []float64{0.2, 1e-9, 0}), "[0.1,0.30000000000000004]x[0,1e-09]x[-3,-3]"},
+	} {
+		if s := tt.r.String(); s != tt.expected {
+			t.Errorf("String() = %q, expected %q", s, tt.expected)
+		}
+	}
 }
 
 func TestRectSize(t *testing.T) {
@@ -148,9 +336,56 @@ func TestRectMargin(t *testing.T) {
 	lengths := []float64{2.5, 8.0, 1.5}
 	rect, _ := NewRect(p, lengths)
 	size := 4*2.5 + 4*8.0 + 4*1.5
-	actual := rect.margin()
+	actual := rect.Margin()
 	if size != actual {
-		t.Errorf("Expected %v.margin() == %v, got %v", rect, size, actual)
+		t.Errorf("Expected %v.Margin() == %v, got %v", rect, size, actual)
+	}
+}
+
+func TestRectUnitCubes(t *testing.T) {
+	tests := []struct {
+		dim    int
+		margin float64
+	}{
+		{1, 1},
+		{2, 4},
+		{3, 12},
+	}
+	for _, tt := range tests {
+		lengths := make([]float64, tt.dim)
+		for i := range lengths {
+			lengths[i] = 1
+		}
+		rect := mustRect(make(Point, tt.dim), lengths)
+		if area := rect.Area(); area != 1 {
+			t.Errorf("Expected %v.Area() == 1, got %v", rect, area)
+		}
+		if margin := rect.Margin(); margin != tt.margin {
+			t.Errorf("Expected %v.Margin() == %v, got %v", rect, tt.margin, margin)
+		}
+	}
+}
+
+func TestRectCenter(t *testing.T) {
+	unit := mustRect(Point{0, 0, 0}, []float64{1, 1, 1})
+	if c := unit.Center(); !slices.Equal(c, Point{0.5, 0.5, 0.5}) {
+		t.Errorf("Expected %v.Center() == [0.5 0.5 0.5], got %v", unit, c)
+	}
+
+	rect := mustRect(Point{1.0, -2.5, 3.0}, []float64{2.5, 8.0, 1.5})
+	c := rect.Center()
+	if len(c) != 3 {
+		t.Fatalf("Expected %v.Center() to have 3 coordinates, got %v", rect, c)
+	}
+	for i := range c {
+		if expected := (rect.PointCoord(i) + rect.PointCoord(i) + rect.LengthsCoord(i)) / 2; c[i] != expected {
+			t.Errorf("Expected %v.Center()[%d] == %v, got %v", rect, i, expected, c[i])
+		}
+	}
+
+	point := Point{4, -1}.ToRect(0)
+	if c := point.Center(); !slices.Equal(c, Point{4, -1}) {
+		t.Errorf("Expected %v.Center() == [4 -1], got %v", point, c)
 	}
 }
 
@@ -160,7 +395,7 @@ func TestContainsPoint(t *testing.T) {
 	rect, _ := NewRect(p, lengths)
 
 	q := Point{4.5, -1.7, 4.8}
-	if yes := rect.containsPoint(q); !yes {
+	if yes := rect.ContainsPoint(q); !yes {
 		t.Errorf("Expected %v contains %v", rect, q)
 	}
 }
@@ -171,7 +406,7 @@ func TestDoesNotContainPoint(t *testing.T) {
 	rect, _ := NewRect(p, lengths)
 
 	q := Point{4.5, -1.7, -3.2}
-	if yes := rect.containsPoint(q); yes {
+	if yes := rect.ContainsPoint(q); yes {
 		t.Errorf("Expected %v doesn't contain %v", rect, q)
 	}
 }
@@ -185,8 +420,8 @@ func TestContainsRect(t *testing.T) {
 	lengths2 := []float64{3.2, 0.6, 3.7}
 	rect2, _ := NewRect(q, lengths2)
 
-	if yes := rect1.containsRect(rect2); !yes {
-		t.Errorf("Expected %v.containsRect(%v", rect1, rect2)
+	if yes := rect1.ContainsRect(rect2); !yes {
+		t.Errorf("Expected %v.ContainsRect(%v", rect1, rect2)
 	}
 }
 
@@ -199,7 +434,7 @@ func TestDoesNotContainRectOverlaps(t *testing.T) {
 	lengths2 := []float64{3.2, 1.4, 3.7}
 	rect2, _ := NewRect(q, lengths2)
 
-	if yes := rect1.containsRect(rect2); yes {
+	if yes := rect1.ContainsRect(rect2); yes {
 		t.Errorf("Expected %v doesn't contain %v", rect1, rect2)
 	}
 }
@@ -213,11 +448,62 @@ func TestDoesNotContainRectDisjoint(t *testing.T) {
 	lengths2 := []float64{2.2, 5.9, 0.5}
 	rect2, _ := NewRect(q, lengths2)
 
-	if yes := rect1.containsRect(rect2); yes {
+	if yes := rect1.ContainsRect(rect2); yes {
 		t.Errorf("Expected %v doesn't contain %v", rect1, rect2)
 	}
 }
 
+func TestContainsEdges(t *testing.T) {
+	r := mustRect(Point{0, 0}, []float64{4, 2})
+	for _, tt := range []struct {
+		other    Rect
+		contains bool
+	}{
+		{r, true},
+		{mustRect(Point{1, 0.5}, []float64{1, 1}), true},
+		{mustRect(Point{0, 0}, []float64{2, 1}), true},
+		{mustRect(Point{2, 1}, []float64{2, 1}), true},
+		{mustRect(Point{0, 2}, []float64{4, 0}), true},
+		{mustRect(Point{2, 1}, []float64{2.5, 1}), false},
+		{mustRect(Point{-1, 0}, []float64{1, 2}), false},
+		{mustRect(Point{-1, -1}, []float64{6, 4}), false},
+	} {
+		if contains := r.ContainsRect(tt.other); contains != tt.contains {
+			t.Errorf("%v.ContainsRect(%v) = %v, expected %v", r, tt.other, contains, tt.contains)
+		}
+	}
+
+	for _, tt := range []struct {
+		p        Point
+		contains bool
+	}{
+		{Point{2, 1}, true},
+		{Point{0, 0}, true},
+		{Point{4, 2}, true},
+		{Point{4, 1}, true},
+		{Point{4.1, 1}, false},
+		{Point{2, -0.1}, false},
+	} {
+		if contains := r.ContainsPoint(tt.p); contains != tt.contains {
+			t.Errorf("%v.ContainsPoint(%v) = %v, expected %v", r, tt.p, contains, tt.contains)
+		}
+	}
+
+	for name, f := range map[string]func(){
+		"ContainsRect":  func() { r.ContainsRect(mustRect(Point{0, 0, 0}, []float64{1, 1, 1})) },
+		"ContainsPoint": func() { r.ContainsPoint(Point{0, 0, 0}) },
+	} {
+		func() {
+			defer func() {
+				if _, ok := recover().(DimError); !ok {
+					t.Errorf("%s didn't panic with a DimError on mismatched dimensions", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
+
 func TestNoIntersection(t *testing.T) {
 	p := Point{1, 2, 3}
 	lengths1 := []float64{1, 1, 1}
@@ -234,7 +520,7 @@ func TestNoIntersection(t *testing.T) {
 	}
 }
 
-func TestNoIntersectionJustTouches(t *testing.T) {
+func TestIntersectionJustTouches(t *testing.T) {
 	p := Point{1, 2, 3}
 	lengths1 := []float64{1, 1, 1}
 	rect1, _ := NewRect(p, lengths1)
@@ -243,10 +529,11 @@ func TestNoIntersectionJustTouches(t *testing.T) {
 	lengths2 := []float64{2.5, 4, 6.5}
 	rect2, _ := NewRect(q, lengths2)
 
-	// rect1 and rect2 fail to overlap in just one dimension (second)
+	// rect1 and rect2 just touch in the second dimension, and rectangles
+	// are closed
 
-	if intersect(rect1, rect2) {
-		t.Errorf("Expected intersect(%v, %v) == false", rect1, rect2)
+	if !intersect(rect1, rect2) {
+		t.Errorf("Expected intersect(%v, %v) == true", rect1, rect2)
 	}
 }
 
@@ -284,6 +571,147 @@ func TestOverlapIntersection(t *testing.T) {
 	}
 }
 
+func TestRectExpand(t *testing.T) {
+	r := mustRect(Point{0, 0}, []float64{2, 6})
+	tests := []struct {
+		margin float64
+		want   Rect
+	}{
+		{1, mustRect(Point{-1, -1}, []float64{4, 8})},
+		{0, r},
+		{-0.5, mustRect(Point{0.5, 0.5}, []float64{1, 5})},
+		{-2, mustRect(Point{1, 2}, []float64{0, 2})},
+		{-10, mustRect(Point{1, 3}, []float64{0, 0})},
+	}
+	for _, tt := range tests {
+		if got := r.Expand(tt.margin); !got.Equal(tt.want) {
+			t.Errorf("%v.Expand(%v) = %v, expected %v", r, tt.margin, got, tt.want)
+		}
+	}
+	if !r.Equal(mustRect(Point{0, 0}, []float64{2, 6})) {
+		t.Errorf("Expand modified the rectangle: %v", r)
+	}
+}
+
+func TestRectTranslate(t *testing.T) {
+	r := mustRect(Point{1, 2}, []float64{2, 6})
+	tests := []struct {
+		delta Point
+		want  Rect
+	}{
+		{Point{0, 0}, r},
+		{Point{3, -4}, mustRect(Point{4, -2}, []float64{2, 6})},
+		{Point{-1.5, 0.5}, mustRect(Point{-0.5, 2.5}, []float64{2, 6})},
+	}
+	for _, tt := range tests {
+		if got := r.Translate(tt.delta); !got.Equal(tt.want) {
+			t.Errorf("%v.Translate(%v) = %v, expected %v", r, tt.delta, got, tt.want)
+		}
+	}
+	if !r.Equal(mustRect(Point{1, 2}, []float64{2, 6})) {
+		t.Errorf("Translate modified the rectangle: %v", r)
+	}
+
+	defer func() {
+		if _, ok := recover().(DimError); !ok {
+			t.Errorf("Translate didn't panic with a DimError on mismatched dimensions")
+		}
+	}()
+	r.Translate(Point{1, 1, 1})
+}
+
+func TestRectScale(t *testing.T) {
+	r := mustRect(Point{1, 2}, []float64{2, 6})
+	tests := []struct {
+		factor float64
+		want   Rect
+	}{
+		{1, r},
+		{2, mustRect(Point{1, 2}, []float64{4, 12})},
+		{0.5, mustRect(Point{1, 2}, []float64{1, 3})},
+		{0, mustRect(Point{1, 2}, []float64{0, 0})},
+	}
+	for _, tt := range tests {
+		if got := r.Scale(tt.factor); !got.Equal(tt.want) {
+			t.Errorf("%v.Scale(%v) = %v, expected %v", r, tt.factor, got, tt.want)
+		}
+	}
+	if !r.Equal(mustRect(Point{1, 2}, []float64{2, 6})) {
+		t.Errorf("Scale modified the rectangle: %v", r)
+	}
+
+	defer func() {
+		if _, ok := recover().(DistError); !ok {
+			t.Errorf("Scale didn't panic with a DistError on a negative factor")
+		}
+	}()
+	r.Scale(-1)
+}
+
+func TestRectIntersects(t *testing.T) {
+	r := mustRect(Point{0, 0}, []float64{2, 2})
+	tests := []struct {
+		name  string
+		other Rect
+		want  bool
+	}{
+		{"overlapping", mustRect(Point{1, 1}, []float64{2, 2}), true},
+		{"disjoint", mustRect(Point{3, 0}, []float64{1, 1}), false},
+		{"disjoint in one dimension", mustRect(Point{1, 2.5}, []float64{1, 1}), false},
+		{"touching on an edge", mustRect(Point{2, 0.5}, []float64{1, 1}), true},
+		{"touching on a corner", mustRect(Point{2, 2}, []float64{1, 1}), true},
+		{"contained", mustRect(Point{0.5, 0.5}, []float64{1, 1}), true},
+		{"containing", mustRect(Point{-1, -1}, []float64{4, 4}), true},
+	}
+	for _, tt := range tests {
+		if got := r.Intersects(tt.other); got != tt.want {
+			t.Errorf("%s: %v.Intersects(%v) = %v, expected %v", tt.name, r, tt.other, got, tt.want)
+		}
+		if got := tt.other.Intersects(r); got != tt.want {
+			t.Errorf("%s: %v.Intersects(%v) = %v, expected %v", tt.name, tt.other, r, got, tt.want)
+		}
+	}
+}
+
+func TestRectIntersectsDimMismatch(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(DimError); !ok {
+			t.Errorf("expected a DimError panic")
+		}
+	}()
+	r := mustRect(Point{0, 0}, []float64{2, 2})
+	r.Intersects(mustRect(Point{0, 0, 0}, []float64{1, 1, 1}))
+}
+
+func TestRectIntersection(t *testing.T) {
+	r := mustRect(Point{0, 0}, []float64{2, 2})
+	tests := []struct {
+		name  string
+		other Rect
+		want  Rect
+		ok    bool
+	}{
+		{"disjoint", mustRect(Point{3, 0}, []float64{1, 1}), Rect{}, false},
+		{"overlapping", mustRect(Point{1, -1}, []float64{2, 2}), Rect{Point{1, 0}, Point{2, 1}}, true},
+		{"contained", mustRect(Point{0.5, 0.5}, []float64{1, 1}), Rect{Point{0.5, 0.5}, Point{1.5, 1.5}}, true},
+		{"touching on a face", mustRect(Point{2, 0.5}, []float64{1, 3}), Rect{Point{2, 0.5}, Point{2, 2}}, true},
+		{"touching on a corner", mustRect(Point{2, 2}, []float64{1, 1}), Rect{Point{2, 2}, Point{2, 2}}, true},
+	}
+	for _, tt := range tests {
+		for _, pair := range [][2]Rect{{r, tt.other}, {tt.other, r}} {
+			got, ok := pair[0].Intersection(pair[1])
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("%s: intersection of %v and %v = %v, %v, expected %v, %v", tt.name, pair[0], pair[1], got, ok, tt.want, tt.ok)
+			}
+		}
+	}
+
+	face, _ := r.Intersection(mustRect(Point{2, 0.5}, []float64{1, 3}))
+	if face.Size() != 0 {
+		t.Errorf("Expected a zero-size intersection on a face, got %v", face.Size())
+	}
+}
+
 func TestToRect(t *testing.T) {
 	x := Point{3.7, -2.4, 0.0}
 	tol := 0.05
@@ -298,6 +726,38 @@ func TestToRect(t *testing.T) {
 	}
 }
 
+func TestToRectDimensions(t *testing.T) {
+	for _, x := range []Point{{1}, {1, 2}, {1, 2, 3, 4}} {
+		rect := x.ToRect(0.5)
+		if len(rect.p) != len(x) || len(rect.q) != len(x) {
+			t.Errorf("Expected %v.ToRect(0.5) to have %d dimensions, got %v", x, len(x), rect)
+		}
+		if !rect.ContainsPoint(x) {
+			t.Errorf("Expected %v.ToRect(0.5) == %v to contain %v", x, rect, x)
+		}
+	}
+}
+
+func TestToRectZeroTolerance(t *testing.T) {
+	x := Point{3.7, -2.4}
+	rect := x.ToRect(0)
+	if x.dist(rect.p) != 0 || x.dist(rect.q) != 0 {
+		t.Errorf("Expected %v.ToRect(0) to be degenerate at %v, got %v", x, x, rect)
+	}
+	if s := rect.Size(); s != 0 {
+		t.Errorf("Expected %v.ToRect(0) to have size 0, got %v", x, s)
+	}
+}
+
+func TestToRectNegativeTolerance(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(DistError); !ok {
+			t.Errorf("Expected ToRect(-1) to panic with a DistError")
+		}
+	}()
+	Point{1, 2}.ToRect(-1)
+}
+
 func TestBoundingBox(t *testing.T) {
 	p := Point{3.7, -2.4, 0.0}
 	lengths1 := []float64{1, 15, 3}
@@ -310,11 +770,11 @@ func TestBoundingBox(t *testing.T) {
 	r := Point{-6.5, -2.4, 0.0}
 	s := Point{4.7, 12.6, 8.5}
 
-	bb := boundingBox(rect1, rect2)
+	bb := rect1.Union(rect2)
 	d1 := r.dist(bb.p)
 	d2 := s.dist(bb.q)
 	if d1 > EPS || d2 > EPS {
-		t.Errorf("boundingBox(%v, %v) != %v, %v, got %v", rect1, rect2, r, s, bb)
+		t.Errorf("%v.Union(%v) != %v, %v, got %v", rect1, rect2, r, s, bb)
 	}
 }
 
@@ -327,14 +787,43 @@ func TestBoundingBoxContains(t *testing.T) {
 	lengths2 := []float64{0.56, 6.222222, 0.946}
 	rect2, _ := NewRect(q, lengths2)
 
-	bb := boundingBox(rect1, rect2)
+	bb := rect1.Union(rect2)
 	d1 := rect1.p.dist(bb.p)
 	d2 := rect1.q.dist(bb.q)
 	if d1 > EPS || d2 > EPS {
-		t.Errorf("boundingBox(%v, %v) != %v, got %v", rect1, rect2, rect1, bb)
+		t.Errorf("%v.Union(%v) != %v, got %v", rect1, rect2, rect1, bb)
 	}
 }
 
+func TestRectUnion(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		r, other Rect
+		expected Rect
+	}{
+		{"disjoint 2D", mustRect(Point{0, 0}, []float64{1, 1}), mustRect(Point{3, -2}, []float64{1, 1}), mustRect(Point{0, -2}, []float64{4, 3})},
+		{"nested 2D", mustRect(Point{0, 0}, []float64{4, 4}), mustRect(Point{1, 1}, []float64{1, 2}), mustRect(Point{0, 0}, []float64{4, 4})},
+		{"disjoint 3D", mustRect(Point{0, 0, 0}, []float64{1, 1, 1}), mustRect(Point{-3, 2, 5}, []float64{1, 1, 1}), mustRect(Point{-3, 0, 0}, []float64{4, 3, 6})},
+		{"nested 3D", mustRect(Point{1, 1, 1}, []float64{1, 1, 0}), mustRect(Point{0, 0, 0}, []float64{3, 3, 3}), mustRect(Point{0, 0, 0}, []float64{3, 3, 3})},
+	} {
+		if u := tt.r.Union(tt.other); !u.Equal(tt.expected) {
+			t.Errorf("%s: %v.Union(%v) = %v, expected %v", tt.name, tt.r, tt.other, u, tt.expected)
+		}
+		if u := tt.other.Union(tt.r); !u.Equal(tt.expected) {
+			t.Errorf("%s: %v.Union(%v) = %v, expected %v", tt.name, tt.other, tt.r, u, tt.expected)
+		}
+	}
+
+	r := mustRect(Point{0, 0}, []float64{1, 1})
+	other := mustRect(Point{0, 0, 0}, []float64{1, 1, 1})
+	defer func() {
+		if err, ok := recover().(DimError); !ok || err.Expected != 2 || err.Actual != 3 {
+			t.Errorf("Union of rectangles of different dimensions panicked with %v, expected a DimError", err)
+		}
+	}()
+	r.Union(other)
+}
+
 func TestMinDistZero(t *testing.T) {
 	p := Point{1, 2, 3}
 	r := p.ToRect(1)
@@ -352,6 +841,53 @@ func TestMinDistPositive(t *testing.T) {
 	}
 }
 
+func TestRectMinDist(t *testing.T) {
+	r := mustRect(Point{0, 0}, []float64{2, 4})
+	for _, tt := range []struct {
+		name     string
+		p        Point
+		expected float64
+	}{
+		{"inside", Point{1, 1}, 0},
+		{"on a face", Point{2, 3}, 0},
+		{"at a corner", Point{0, 4}, 0},
+		{"beside a face", Point{-3, 2}, 9},
+		{"beyond a corner", Point{5, 8}, 3*3 + 4*4},
+		{"far outside", Point{-1000, 2}, 1000 * 1000},
+	} {
+		if d := r.MinDist(tt.p); d != tt.expected {
+			t.Errorf("%s: %v.MinDist(%v) = %v, expected %v", tt.name, r, tt.p, d, tt.expected)
+		}
+	}
+
+	defer func() {
+		if err, ok := recover().(DimError); !ok || err.Expected != 2 || err.Actual != 3 {
+			t.Errorf("MinDist with a point of another dimension panicked with %v, expected a DimError", err)
+		}
+	}()
+	r.MinDist(Point{1, 1, 1})
+}
+
+func TestDistToIntersecting(t *testing.T) {
+	r1 := Rect{Point{0, 0}, Point{2, 2}}
+	r2 := Rect{Point{1, 1}, Point{3, 3}}
+	if d := r1.DistTo(r2); d != 0 {
+		t.Errorf("Expected %v.DistTo(%v) == 0, got %v", r1, r2, d)
+	}
+}
+
+func TestDistToDisjoint(t *testing.T) {
+	r1 := Rect{Point{0, 0, 0}, Point{1, 1, 1}}
+	r2 := Rect{Point{4, -5, 0.5}, Point{5, -4, 2}}
+	expected := 5.0 // the gap is (3, 4, 0)
+	if d := r1.DistTo(r2); math.Abs(d-expected) > EPS {
+		t.Errorf("Expected %v.DistTo(%v) == %v, got %v", r1, r2, expected, d)
+	}
+	if d := r2.DistTo(r1); math.Abs(d-expected) > EPS {
+		t.Errorf("Expected %v.DistTo(%v) == %v, got %v", r2, r1, expected, d)
+	}
+}
+
 func TestMinMaxdist(t *testing.T) {
 	p := Point{-3, -2, -1}
 	r := Rect{Point{0, 0, 0}, Point{1, 2, 3}}
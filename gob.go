@@ -0,0 +1,164 @@
+package rtreego
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// gobTree is the serialized form of an Rtree.
+type gobTree struct {
+	Dim                 int
+	MinChildren         int
+	MaxChildren         int
+	SplitStrategy       SplitStrategy
+	RotateSplits        bool
+	LazyBounds          bool
+	LinearScanThreshold int
+	Epsilon             float64
+	HalfOpen            bool
+	RStar               bool
+	ReinsertPercent     int
+	Size                int
+	Root                *gobNode
+}
+
+// gobNode is the serialized form of a node.  Interior nodes only store their
+// children, since their bounding boxes can be computed from the leaves.
+type gobNode struct {
+	Leaf     bool
+	Level    int
+	Children []*gobNode
+	Objects  []gobObject
+}
+
+// gobObject is the serialized form of a leaf entry.
+type gobObject struct {
+	Min, Max Point
+	Obj      Spatial
+}
+
+// GobEncode implements gob.GobEncoder, serializing the configuration of tree
+// and its full structure, including the stored objects, so that it can be
+// restored by GobDecode without being built again.  The objects are encoded as
+// values of the Spatial interface, so their concrete types must be registered
+// with gob.Register beforehand, both when encoding and decoding, and must be
+// encodable by gob.  The hook registered with OnMutation isn't serialized.
+func (tree *Rtree) GobEncode() ([]byte, error) {
+	t := gobTree{
+		Dim:                 tree.Dim,
+		MinChildren:         tree.MinChildren,
+		MaxChildren:         tree.MaxChildren,
+		SplitStrategy:       tree.SplitStrategy,
+		RotateSplits:        tree.RotateSplits,
+		LazyBounds:          tree.LazyBounds,
+		LinearScanThreshold: tree.LinearScanThreshold,
+		Epsilon:             tree.Epsilon,
+		HalfOpen:            tree.HalfOpen,
+		RStar:               tree.rstar,
+		ReinsertPercent:     tree.ReinsertPercent,
+		Size:                tree.size,
+		Root:                encodeNode(tree.root),
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&t); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeNode(n *node) *gobNode {
+	gn := &gobNode{Leaf: n.leaf, Level: n.level}
+	for _, e := range n.entries {
+		if n.leaf {
+			gn.Objects = append(gn.Objects, gobObject{e.bb.p, e.bb.q, e.obj})
+		} else {
+			gn.Children = append(gn.Children, encodeNode(e.child))
+		}
+	}
+	return gn
+}
+
+// GobDecode implements gob.GobDecoder, replacing the configuration and the
+// contents of tree by the ones serialized in data by GobEncode.  Parent
+// pointers and interior bounding boxes are rebuilt from the decoded nodes.
+// GobDecode returns an error and leaves tree unchanged if the decoded nodes
+// don't form a tree of consistent levels and dimension holding Size objects.
+func (tree *Rtree) GobDecode(data []byte) error {
+	var t gobTree
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&t); err != nil {
+		return err
+	}
+	if t.Root == nil {
+		return fmt.Errorf("rtreego: encoded tree has no root")
+	}
+
+	decoded := &Rtree{
+		Dim:                 t.Dim,
+		MinChildren:         t.MinChildren,
+		MaxChildren:         t.MaxChildren,
+		SplitStrategy:       t.SplitStrategy,
+		RotateSplits:        t.RotateSplits,
+		LazyBounds:          t.LazyBounds,
+		LinearScanThreshold: t.LinearScanThreshold,
+		Epsilon:             t.Epsilon,
+		HalfOpen:            t.HalfOpen,
+		rstar:               t.RStar,
+		ReinsertPercent:     t.ReinsertPercent,
+		size:                t.Size,
+		height:              t.Root.Level,
+	}
+	root, size, err := decoded.decodeNode(t.Root)
+	if err != nil {
+		return err
+	}
+	if size != t.Size {
+		return fmt.Errorf("rtreego: encoded tree holds %d objects but its size is %d", size, t.Size)
+	}
+	root.recount()
+	decoded.root = root
+
+	decoded.onMutation = tree.onMutation
+	*tree = *decoded
+	return nil
+}
+
+// decodeNode rebuilds the subtree gn and returns it along with the number of
+// objects it holds.
+func (tree *Rtree) decodeNode(gn *gobNode) (*node, int, error) {
+	n := &node{leaf: gn.Leaf, level: gn.Level, entries: []entry{}}
+	if gn.Leaf {
+		if gn.Level != 1 {
+			return nil, 0, fmt.Errorf("rtreego: encoded leaf at level %d", gn.Level)
+		}
+		for _, o := range gn.Objects {
+			if len(o.Min) != tree.Dim {
+				return nil, 0, &DimError{tree.Dim, len(o.Min)}
+			}
+			bb, err := NewRectFromPoints(o.Min, o.Max)
+			if err != nil {
+				return nil, 0, err
+			}
+			n.entries = append(n.entries, entry{bb: bb, obj: o.Obj})
+		}
+		return n, len(n.entries), nil
+	}
+
+	size := 0
+	for _, gc := range gn.Children {
+		if gc == nil || gc.Level != gn.Level-1 {
+			return nil, 0, fmt.Errorf("rtreego: encoded interior node at level %d has an invalid child", gn.Level)
+		}
+		child, childSize, err := tree.decodeNode(gc)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(child.entries) == 0 {
+			return nil, 0, fmt.Errorf("rtreego: encoded interior node at level %d has an empty child", gn.Level)
+		}
+		child.parent = n
+		n.entries = append(n.entries, tree.childEntry(child))
+		size += childSize
+	}
+	return n, size, nil
+}
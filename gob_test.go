@@ -0,0 +1,97 @@
+package rtreego
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math/rand"
+	"testing"
+)
+
+// gobThing is a Spatial with exported fields, which gob can encode.
+type gobThing struct {
+	ID       int
+	Min, Max Point
+}
+
+func (t *gobThing) Bounds() Rect {
+	r, _ := NewRectFromPoints(t.Min, t.Max)
+	return r
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	gob.Register(&gobThing{})
+
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i, thing := range randomRects(rnd, 100) {
+		r := thing.(*Rect)
+		things = append(things, &gobThing{i, r.p, r.q})
+	}
+
+	for _, tc := range tests(2, 3, 6, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(rt); err != nil {
+				t.Fatalf("Encode() = %v", err)
+			}
+			decoded := &Rtree{}
+			if err := gob.NewDecoder(&buf).Decode(decoded); err != nil {
+				t.Fatalf("Decode() = %v", err)
+			}
+
+			verify(t, decoded)
+			checkCounts(t, decoded.root)
+			if decoded.Size() != rt.Size() || decoded.Depth() != rt.Depth() {
+				t.Errorf("decoded tree has size %d and depth %d, expected %d and %d",
+					decoded.Size(), decoded.Depth(), rt.Size(), rt.Depth())
+			}
+			if decoded.Dim != rt.Dim || decoded.MinChildren != rt.MinChildren || decoded.MaxChildren != rt.MaxChildren {
+				t.Errorf("decoded tree has parameters %d, %d, %d, expected %d, %d, %d",
+					decoded.Dim, decoded.MinChildren, decoded.MaxChildren, rt.Dim, rt.MinChildren, rt.MaxChildren)
+			}
+
+			for i := 0; i < 20; i++ {
+				bb := Point{rnd.Float64() * 100, rnd.Float64() * 100}.ToRect(10)
+				expected := ids(rt.SearchIntersect(bb))
+				actual := ids(decoded.SearchIntersect(bb))
+				if len(actual) != len(expected) {
+					t.Fatalf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(actual), len(expected))
+				}
+				for id := range expected {
+					if !actual[id] {
+						t.Errorf("SearchIntersect(%v) on the decoded tree misses object %d", bb, id)
+					}
+				}
+			}
+
+			// the decoded tree remains usable
+			decoded.Insert(&gobThing{-1, Point{0, 0}, Point{1, 1}})
+			if !decoded.DeleteWithComparator(things[0], func(a, b Spatial) bool {
+				return a.(*gobThing).ID == b.(*gobThing).ID
+			}) {
+				t.Errorf("failed to delete %v from the decoded tree", things[0])
+			}
+			verify(t, decoded)
+		})
+	}
+}
+
+func TestGobDecodeInvalid(t *testing.T) {
+	rt := NewTree(2, 3, 8)
+	if err := rt.GobDecode([]byte("garbage")); err == nil {
+		t.Errorf("GobDecode() = nil on invalid data")
+	}
+	if err := rt.Validate(); err != nil {
+		t.Errorf("Validate() = %v after a failed GobDecode", err)
+	}
+}
+
+func ids(objs []Spatial) map[int]bool {
+	m := make(map[int]bool, len(objs))
+	for _, obj := range objs {
+		m[obj.(*gobThing).ID] = true
+	}
+	return m
+}
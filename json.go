@@ -0,0 +1,57 @@
+package rtreego
+
+import "encoding/json"
+
+// jsonNode is the JSON representation of a node.  The order of the fields
+// sets the order of the keys in the output.
+type jsonNode struct {
+	Level    int          `json:"level"`
+	Leaf     bool         `json:"leaf"`
+	Min      Point        `json:"min,omitempty"`
+	Lengths  []float64    `json:"lengths,omitempty"`
+	Count    int          `json:"count"`
+	Children []*jsonNode  `json:"children,omitempty"`
+	Objects  []jsonBounds `json:"objects,omitempty"`
+}
+
+// jsonBounds is the JSON representation of the bounds of a leaf entry.
+type jsonBounds struct {
+	Min     Point     `json:"min"`
+	Lengths []float64 `json:"lengths"`
+}
+
+// MarshalJSON implements json.Marshaler, describing the hierarchy of bounding
+// boxes of tree for debugging and visualization.  Each node is an object with
+// the keys "level", "leaf", "min" and "lengths", the corner and side lengths
+// of its MBR, "count", its number of entries, and either "children", its
+// child nodes, or "objects", the bounds of the objects stored in a leaf.  The
+// MBR of an empty root is omitted.  The stored objects themselves aren't
+// serialized.
+func (tree *Rtree) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonNodeOf(tree.root))
+}
+
+func jsonNodeOf(n *node) *jsonNode {
+	jn := &jsonNode{Level: n.level, Leaf: n.leaf, Count: len(n.entries)}
+	if len(n.entries) > 0 {
+		jn.Min, jn.Lengths = jsonRect(n.computeBoundingBox())
+	}
+	for _, e := range n.entries {
+		if n.leaf {
+			min, lengths := jsonRect(e.bb)
+			jn.Objects = append(jn.Objects, jsonBounds{min, lengths})
+		} else {
+			jn.Children = append(jn.Children, jsonNodeOf(e.child))
+		}
+	}
+	return jn
+}
+
+// jsonRect returns the corner and side lengths of r.
+func jsonRect(r Rect) (Point, []float64) {
+	lengths := make([]float64, len(r.p))
+	for i := range lengths {
+		lengths[i] = r.LengthsCoord(i)
+	}
+	return r.p, lengths
+}
@@ -0,0 +1,68 @@
+package rtreego
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 100)
+	rt := NewTree(2, 3, 8, things...)
+
+	data, err := json.Marshal(rt)
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"level":`) {
+		t.Errorf("output doesn't start with the level: %s", data[:20])
+	}
+
+	var root map[string]interface{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("Unmarshal() = %v", err)
+	}
+	mbr := rt.root.computeBoundingBox()
+	min := root["min"].([]interface{})
+	lengths := root["lengths"].([]interface{})
+	for i := 0; i < 2; i++ {
+		if min[i].(float64) != mbr.PointCoord(i) || lengths[i].(float64) != mbr.LengthsCoord(i) {
+			t.Errorf("root MBR is %v + %v, expected %v", min, lengths, mbr)
+		}
+	}
+	if root["level"].(float64) != float64(rt.Depth()) || root["leaf"].(bool) {
+		t.Errorf("root has level %v and leaf %v in a tree of depth %d", root["level"], root["leaf"], rt.Depth())
+	}
+
+	// count the objects in the leaves
+	var count func(n map[string]interface{}) int
+	count = func(n map[string]interface{}) int {
+		if n["leaf"].(bool) {
+			return len(n["objects"].([]interface{}))
+		}
+		total := 0
+		for _, c := range n["children"].([]interface{}) {
+			total += count(c.(map[string]interface{}))
+		}
+		return total
+	}
+	if c := count(root); c != rt.Size() {
+		t.Errorf("output holds %d objects, expected %d", c, rt.Size())
+	}
+
+	again, _ := json.Marshal(rt)
+	if string(again) != string(data) {
+		t.Errorf("output isn't stable")
+	}
+}
+
+func TestMarshalJSONEmpty(t *testing.T) {
+	data, err := json.Marshal(NewTree(2, 3, 8))
+	if err != nil {
+		t.Fatalf("Marshal() = %v", err)
+	}
+	if expected := `{"level":1,"leaf":true,"count":0}`; string(data) != expected {
+		t.Errorf("Marshal() = %s, expected %s", data, expected)
+	}
+}
@@ -0,0 +1,127 @@
+package rtreego
+
+import "sync"
+
+// LockedRtree wraps an Rtree to make it safe for concurrent use.  Queries may
+// run concurrently with each other, while Insert, Delete, Update and Load
+// exclude any other operation.
+//
+// The wrapped tree must not be used directly while it is shared through a
+// LockedRtree.  Methods that aren't wrapped can be called through Read and
+// Write.
+type LockedRtree struct {
+	mu   sync.RWMutex
+	tree *Rtree
+}
+
+// NewLockedRtree returns a LockedRtree wrapping tree.
+func NewLockedRtree(tree *Rtree) *LockedRtree {
+	return &LockedRtree{tree: tree}
+}
+
+// Read calls f with the wrapped tree while holding the read lock.  f must not
+// modify the tree.
+func (lt *LockedRtree) Read(f func(tree *Rtree)) {
+	lt.mu.RLock()
+	defer lt.mu.RUnlock()
+	f(lt.tree)
+}
+
+// Write calls f with the wrapped tree while holding the write lock.
+func (lt *LockedRtree) Write(f func(tree *Rtree)) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	f(lt.tree)
+}
+
+// Size returns the number of objects currently stored in the tree.
+func (lt *LockedRtree) Size() (size int) {
+	lt.Read(func(tree *Rtree) { size = tree.Size() })
+	return
+}
+
+// Depth returns the maximum depth of the tree.
+func (lt *LockedRtree) Depth() (depth int) {
+	lt.Read(func(tree *Rtree) { depth = tree.Depth() })
+	return
+}
+
+// String returns a multi-line rendering of the tree for debugging.
+func (lt *LockedRtree) String() (s string) {
+	lt.Read(func(tree *Rtree) { s = tree.String() })
+	return
+}
+
+// Insert inserts a spatial object into the tree.
+func (lt *LockedRtree) Insert(obj Spatial) (err error) {
+	lt.Write(func(tree *Rtree) { err = tree.Insert(obj) })
+	return
+}
+
+// Load bulk loads objs into the tree.
+func (lt *LockedRtree) Load(objs ...Spatial) {
+	lt.Write(func(tree *Rtree) { tree.Load(objs...) })
+}
+
+// Rebuild repacks the objects stored in the tree.
+func (lt *LockedRtree) Rebuild() {
+	lt.Write(func(tree *Rtree) { tree.Rebuild() })
+}
+
+// Merge inserts all objects of other into the tree.  other must not be
+// modified concurrently.
+func (lt *LockedRtree) Merge(other *Rtree) (err error) {
+	lt.Write(func(tree *Rtree) { err = tree.Merge(other) })
+	return
+}
+
+// Contains reports whether obj is stored in the tree.
+func (lt *LockedRtree) Contains(obj Spatial) (found bool) {
+	lt.Read(func(tree *Rtree) { found = tree.Contains(obj) })
+	return
+}
+
+// Delete removes an object from the tree and reports whether it was found.
+func (lt *LockedRtree) Delete(obj Spatial) (found bool) {
+	lt.Write(func(tree *Rtree) { found = tree.Delete(obj) })
+	return
+}
+
+// DeleteWithComparator removes an object from the tree using a custom
+// comparator and reports whether it was found.
+func (lt *LockedRtree) DeleteWithComparator(obj Spatial, cmp Comparator) (found bool) {
+	lt.Write(func(tree *Rtree) { found = tree.DeleteWithComparator(obj, cmp) })
+	return
+}
+
+// Update changes the bounds under which obj is stored and reports whether it
+// was found.
+func (lt *LockedRtree) Update(obj Spatial, newBounds Rect) (found bool, err error) {
+	lt.Write(func(tree *Rtree) { found, err = tree.Update(obj, newBounds) })
+	return
+}
+
+// SearchIntersect returns all objects that intersect the specified rectangle.
+func (lt *LockedRtree) SearchIntersect(bb Rect, filters ...Filter) (objs []Spatial) {
+	lt.Read(func(tree *Rtree) { objs = tree.SearchIntersect(bb, filters...) })
+	return
+}
+
+// SearchContained returns all objects whose bounds are contained in the
+// specified rectangle.
+func (lt *LockedRtree) SearchContained(bb Rect, filters ...Filter) (objs []Spatial) {
+	lt.Read(func(tree *Rtree) { objs = tree.SearchContained(bb, filters...) })
+	return
+}
+
+// NearestNeighbor returns the closest object to the specified point.
+func (lt *LockedRtree) NearestNeighbor(p Point) (obj Spatial) {
+	lt.Read(func(tree *Rtree) { obj = tree.NearestNeighbor(p) })
+	return
+}
+
+// NearestNeighbors gets the k closest Spatials to the Point.
+func (lt *LockedRtree) NearestNeighbors(k int, p Point, filters ...Filter) (objs []Spatial) {
+	lt.Read(func(tree *Rtree) { objs = tree.NearestNeighbors(k, p, filters...) })
+	return
+}
@@ -0,0 +1,47 @@
+package rtreego
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestLockedRtreeConcurrent(t *testing.T) {
+	for _, lazy := range []bool{false, true} {
+		rt := NewTree(2, 3, 8)
+		rt.LazyBounds = lazy
+		lt := NewLockedRtree(rt)
+
+		const writers, readers, inserts = 4, 4, 250
+		var wg sync.WaitGroup
+		for w := 0; w < writers; w++ {
+			things := randomRects(rand.New(rand.NewSource(int64(w))), inserts)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, thing := range things {
+					lt.Insert(thing)
+				}
+			}()
+		}
+		for r := 0; r < readers; r++ {
+			rnd := rand.New(rand.NewSource(int64(writers + r)))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < inserts; i++ {
+					p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+					lt.NearestNeighbors(3, p)
+					lt.SearchIntersect(p.ToRect(5))
+					lt.Size()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if size := lt.Size(); size != writers*inserts {
+			t.Errorf("Size() = %d with LazyBounds = %v, expected %d", size, lazy, writers*inserts)
+		}
+		lt.Read(func(tree *Rtree) { verify(t, tree) })
+	}
+}
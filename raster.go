@@ -0,0 +1,144 @@
+package rtreego
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rasterize overlays a grid of cellsPerAxis x cellsPerAxis cells on the
+// rectangle within and returns, for every cell, the number of objects whose
+// bounds intersect it.  It only supports two-dimensional trees.
+//
+// The result is indexed as grid[i][j], where i is the index of the cell along
+// the first axis and j along the second one.  Cells are half-open: the cell
+// grid[i][j] covers [x0+i*w, x0+(i+1)*w) x [y0+j*h, y0+(j+1)*h), where (x0,
+// y0) is the lower corner of within and w and h are the lengths of the cells,
+// except that the last cells along each axis also include the upper edge of
+// within.  An object touching a cell's lower edge is thus counted in that
+// cell, while one touching its upper edge is counted in the next one.
+//
+// Rasterize returns a *DimError if the tree or within isn't two-dimensional,
+// and an error if cellsPerAxis is less than 1.
+func (tree *Rtree) Rasterize(cellsPerAxis int, within Rect) ([][]int, error) {
+	if tree.Dim != 2 {
+		return nil, &DimError{2, tree.Dim}
+	}
+	if len(within.p) != 2 {
+		return nil, &DimError{2, len(within.p)}
+	}
+	if cellsPerAxis < 1 {
+		return nil, fmt.Errorf("rtreego: invalid number of cells per axis %d", cellsPerAxis)
+	}
+
+	grid := make([][]int, cellsPerAxis)
+	for i := range grid {
+		grid[i] = make([]int, cellsPerAxis)
+	}
+	tree.rasterize(grid, tree.root, within)
+	return grid, nil
+}
+
+func (tree *Rtree) rasterize(grid [][]int, n *node, within Rect) {
+	for _, e := range n.entries {
+		bb := e.bounds()
+		if bb.q[0] < within.p[0] || bb.p[0] > within.q[0] ||
+			bb.q[1] < within.p[1] || bb.p[1] > within.q[1] {
+			continue
+		}
+
+		if !n.leaf {
+			tree.rasterize(grid, e.child, within)
+			continue
+		}
+
+		i0, i1 := cellRange(bb, within, 0, len(grid))
+		j0, j1 := cellRange(bb, within, 1, len(grid))
+		for i := i0; i <= i1; i++ {
+			for j := j0; j <= j1; j++ {
+				grid[i][j]++
+			}
+		}
+	}
+}
+
+// cellRange returns the indices of the first and last cells along dimension
+// dim that bb intersects, when within is divided into n cells along dim.
+func cellRange(bb, within Rect, dim, n int) (int, int) {
+	lo, hi := within.p[dim], within.q[dim]
+	return cellIndex(math.Max(bb.p[dim], lo), lo, hi, n), cellIndex(math.Min(bb.q[dim], hi), lo, hi, n)
+}
+
+// cellIndex returns the index of the cell containing x when [lo, hi] is
+// divided into n half-open cells, the last one including hi.  Coordinates
+// outside of [lo, hi] are clamped to the first or last cell.
+func cellIndex(x, lo, hi float64, n int) int {
+	w := (hi - lo) / float64(n)
+	if w == 0 {
+		return 0
+	}
+	i := int(math.Floor((x - lo) / w))
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// Histogram divides region into a grid of bins[d] cells along every
+// dimension d and returns the number of objects whose center lies in each
+// cell.  The counts are flattened in row-major order: the cell of indices
+// (i0, i1, ..., ik) is at i0*bins[1]*...*bins[k] + ... + ik, so that the
+// last dimension varies fastest.  Cells are half-open like in Rasterize, with
+// the last cells along each dimension including the upper edge of region,
+// and objects whose center lies outside of region aren't counted.
+//
+// Histogram returns a *DimError if region or bins don't have Dim dimensions,
+// and an error if some number of bins is less than 1.
+func (tree *Rtree) Histogram(region Rect, bins []int) ([]int, error) {
+	if len(bins) != tree.Dim {
+		return nil, &DimError{tree.Dim, len(bins)}
+	}
+	if len(region.p) != tree.Dim {
+		return nil, &DimError{tree.Dim, len(region.p)}
+	}
+	cells := 1
+	for d, b := range bins {
+		if b < 1 {
+			return nil, fmt.Errorf("rtreego: invalid number of bins %d along dimension %d", b, d)
+		}
+		cells *= b
+	}
+
+	counts := make([]int, cells)
+	tree.histogram(counts, tree.root, region, bins)
+	return counts, nil
+}
+
+func (tree *Rtree) histogram(counts []int, n *node, region Rect, bins []int) {
+	for _, e := range n.entries {
+		bb := e.bounds()
+		if !intersect(bb, region) {
+			continue
+		}
+
+		if !n.leaf {
+			tree.histogram(counts, e.child, region, bins)
+			continue
+		}
+
+		cell := 0
+		for d, b := range bins {
+			c := (bb.p[d] + bb.q[d]) / 2
+			if c < region.p[d] || c > region.q[d] {
+				cell = -1
+				break
+			}
+			cell = cell*b + cellIndex(c, region.p[d], region.q[d], b)
+		}
+		if cell >= 0 {
+			counts[cell]++
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package rtreego
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestRasterize(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{0.5, 0.5}, []float64{0.2, 0.2}), // cell (0, 0)
+		mustRect(Point{1.5, 0.5}, []float64{1, 0.2}),   // cells (1, 0) and (2, 0)
+		mustRect(Point{1, 3}, []float64{1, 1}),         // cells (1, 3) and (2, 3)
+		mustRect(Point{3.5, 3.5}, []float64{2, 2}),     // partially outside, cell (3, 3)
+		mustRect(Point{-5, -5}, []float64{1, 1}),       // outside
+		mustRect(Point{0, 0}, []float64{4, 4}),         // covers everything
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	expected := [][]int{
+		{2, 1, 1, 1},
+		{2, 1, 1, 2},
+		{2, 1, 1, 2},
+		{1, 1, 1, 2},
+	}
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			grid, err := rt.Rasterize(4, mustRect(Point{0, 0}, []float64{4, 4}))
+			if err != nil {
+				t.Fatalf("Rasterize() = %v", err)
+			}
+			if len(grid) != 4 {
+				t.Fatalf("Rasterize returned %d columns, expected 4", len(grid))
+			}
+			for i := range expected {
+				for j := range expected[i] {
+					if grid[i][j] != expected[i][j] {
+						t.Errorf("cell (%d, %d) has count %d, expected %d", i, j, grid[i][j], expected[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRasterizeInvalid(t *testing.T) {
+	rt := NewTree(3, 3, 3)
+	if _, err := rt.Rasterize(4, mustRect(Point{0, 0, 0}, []float64{1, 1, 1})); err == nil {
+		t.Errorf("Expected an error on Rasterize of a 3D tree")
+	} else if _, ok := err.(*DimError); !ok {
+		t.Errorf("Rasterize of a 3D tree = %v, expected a *DimError", err)
+	}
+
+	rt = NewTree(2, 3, 3)
+	if _, err := rt.Rasterize(4, mustRect(Point{0, 0, 0}, []float64{1, 1, 1})); err == nil {
+		t.Errorf("Expected an error on Rasterize within a 3D rectangle")
+	}
+	if _, err := rt.Rasterize(0, mustRect(Point{0, 0}, []float64{1, 1})); err == nil {
+		t.Errorf("Expected an error on Rasterize with no cells")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	points := make([]Rect, 20000)
+	things := make([]Spatial, len(points))
+	for i := range points {
+		points[i] = Point{rnd.Float64() * 100, rnd.Float64() * 50}.ToRect(0)
+		things[i] = &points[i]
+	}
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			counts, err := rt.Histogram(mustRect(Point{0, 0}, []float64{100, 50}), []int{5, 4})
+			if err != nil {
+				t.Fatalf("Histogram() = %v", err)
+			}
+			if len(counts) != 20 {
+				t.Fatalf("Histogram returned %d counts, expected 20", len(counts))
+			}
+			total := 0
+			for i, c := range counts {
+				total += c
+				// 1000 points are expected per bin
+				if c < 850 || c > 1150 {
+					t.Errorf("bin %d holds %d points, expected about 1000", i, c)
+				}
+			}
+			if total != len(points) {
+				t.Errorf("Histogram counted %d points, expected %d", total, len(points))
+			}
+		})
+	}
+}
+
+func TestHistogramCells(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{0.5, 0.5}, []float64{0.2, 0.2}), // cell (0, 0)
+		mustRect(Point{1, 2.5}, []float64{1, 1}),       // center (1.5, 3), cell (0, 1)
+		mustRect(Point{2, 2}, []float64{0, 0}),         // lower edge of cell (1, 1)
+		mustRect(Point{4, 4}, []float64{0, 0}),         // upper edge of region, cell (1, 1)
+		mustRect(Point{3, -1}, []float64{2, 2}),        // center (4, 0) on the edge, cell (1, 0)
+		mustRect(Point{-2, 0}, []float64{2, 2}),        // center (-1, 1) outside
+	}
+	var things []Spatial
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+	rt := NewTree(2, 3, 3, things...)
+
+	counts, err := rt.Histogram(mustRect(Point{0, 0}, []float64{4, 4}), []int{2, 2})
+	if err != nil {
+		t.Fatalf("Histogram() = %v", err)
+	}
+	if expected := []int{1, 1, 1, 2}; !slices.Equal(counts, expected) {
+		t.Errorf("Histogram() = %v, expected %v", counts, expected)
+	}
+
+	var dimErr *DimError
+	if _, err := rt.Histogram(mustRect(Point{0, 0}, []float64{4, 4}), []int{2, 2, 2}); !errors.As(err, &dimErr) {
+		t.Errorf("Histogram() = %v with 3 numbers of bins, expected a *DimError", err)
+	}
+	if _, err := rt.Histogram(mustRect(Point{0, 0, 0}, []float64{4, 4, 4}), []int{2, 2}); !errors.As(err, &dimErr) {
+		t.Errorf("Histogram() = %v with a 3D region, expected a *DimError", err)
+	}
+	if _, err := rt.Histogram(mustRect(Point{0, 0}, []float64{4, 4}), []int{2, 0}); err == nil {
+		t.Errorf("Histogram() = nil with 0 bins")
+	}
+}
@@ -6,9 +6,14 @@
 package rtreego
 
 import (
+	"cmp"
+	"container/heap"
 	"fmt"
+	"iter"
 	"math"
+	"slices"
 	"sort"
+	"strings"
 )
 
 // Comparator compares two spatials and returns whether they are equal.
@@ -18,16 +23,190 @@ func defaultComparator(obj1, obj2 Spatial) bool {
 	return obj1 == obj2
 }
 
+// MutationKind identifies the kind of change described by a MutationEvent.
+type MutationKind int
+
+const (
+	// InsertMutation reports that an object was inserted.
+	InsertMutation MutationKind = iota
+	// DeleteMutation reports that an object was deleted.
+	DeleteMutation
+	// SplitMutation reports that a node was split in two.
+	SplitMutation
+	// UpdateMutation reports that the bounds of an object were changed.
+	UpdateMutation
+)
+
+// MutationEvent describes a change to a tree.  For inserts and deletes, Object
+// is the affected object and Bounds holds its bounding box.  For updates,
+// Object is the affected object and Bounds holds its previous and new
+// bounding boxes.  For splits, Object is nil and Bounds holds the bounding
+// boxes of the two nodes resulting from the split.
+type MutationEvent struct {
+	Kind   MutationKind
+	Object Spatial
+	Bounds []Rect
+}
+
+// SplitStrategy selects the algorithm used to split overflowing nodes.
+type SplitStrategy int
+
+const (
+	// QuadraticSplit is the quadratic split of Guttman's R-tree, which
+	// grows two groups from the pair of entries that would waste the most
+	// area together.
+	QuadraticSplit SplitStrategy = iota
+	// RStarSplit is the split of the R*-tree, which sorts the entries along
+	// the axis minimizing the margins of the groups and then cuts them where
+	// the groups overlap the least.
+	RStarSplit
+	// LinearSplit is the linear split of Guttman's R-tree, which grows two
+	// groups from the pair of entries that are the farthest apart along some
+	// axis.  It yields lower quality trees than QuadraticSplit but is much
+	// faster for large values of MaxChildren.
+	LinearSplit
+)
+
+// Splitter is implemented by custom algorithms for splitting overflowing
+// nodes, set in the Splitter field of an Rtree.  Split is given the bounding
+// boxes of the entries of a node, which it must not modify, and returns for
+// each of them whether it goes to the first of the two new nodes.  Both
+// groups must hold at least minGroupSize entries.  SplitStrategy implements
+// Splitter, so that custom algorithms can fall back to the built-in ones.
+type Splitter interface {
+	Split(bounds []Rect, minGroupSize int) (first []bool)
+}
+
+// SplitError is an invalid split returned by a Splitter.  It implements the
+// error interface and is the value of the panic caused by the split, which
+// leaves the node being split unchanged.
+type SplitError struct {
+	Entries     int // number of entries to split
+	Assigned    int // number of entries assigned to a group by the Splitter
+	First       int // size of the first group
+	Second      int // size of the second group
+	MinChildren int
+}
+
+func (err SplitError) Error() string {
+	if err.Assigned != err.Entries {
+		return fmt.Sprintf("rtreego: Splitter assigned %d of %d entries", err.Assigned, err.Entries)
+	}
+	return fmt.Sprintf("rtreego: Splitter returned groups of %d and %d entries, expected at least %d",
+		err.First, err.Second, err.MinChildren)
+}
+
+// Split implements Splitter with the algorithm selected by s.
+func (s SplitStrategy) Split(bounds []Rect, minGroupSize int) (first []bool) {
+	n := &node{leaf: true, level: 1, entries: make([]entry, len(bounds))}
+	for i, bb := range bounds {
+		n.entries[i] = entry{bb: bb, obj: splitIndex(i)}
+	}
+	left, _ := s.split(n, minGroupSize)
+	first = make([]bool, len(bounds))
+	for _, e := range left.entries {
+		first[e.obj.(splitIndex)] = true
+	}
+	return first
+}
+
+// split splits the node n with the algorithm selected by s.
+func (s SplitStrategy) split(n *node, minGroupSize int) (left, right *node) {
+	switch s {
+	case RStarSplit:
+		return n.splitRStar(minGroupSize)
+	case LinearSplit:
+		return n.splitLinear(minGroupSize)
+	default:
+		return n.split(minGroupSize)
+	}
+}
+
+// splitIndex identifies the entries split by SplitStrategy.Split.
+type splitIndex int
+
+func (splitIndex) Bounds() Rect { return Rect{} }
+
 // Rtree represents an R-tree, a balanced search tree for storing and querying
 // spatial objects.  Dim specifies the number of spatial dimensions and
 // MinChildren/MaxChildren specify the minimum/maximum branching factors.
+// Queries panic with a DimError if the query rectangle or point doesn't have
+// Dim dimensions.
 type Rtree struct {
 	Dim         int
 	MinChildren int
 	MaxChildren int
-	root        *node
-	size        int
-	height      int
+
+	// SplitStrategy selects how overflowing nodes are split.  It defaults to
+	// QuadraticSplit.
+	SplitStrategy SplitStrategy
+
+	// Splitter, if not nil, splits overflowing nodes in place of
+	// SplitStrategy.  It isn't serialized by GobEncode.
+	Splitter Splitter
+
+	// RotateSplits enables local rebalancing after splits: when the two
+	// nodes resulting from a split overlap, entries are moved between them
+	// as long as this reduces their overlap without growing their area.  It
+	// is cheaper than forced reinsertion since it never touches nodes besides
+	// the split siblings.
+	RotateSplits bool
+
+	// LazyBounds disables storing the bounding boxes of interior entries,
+	// which are then computed from their children whenever they are needed.
+	// This trades query time for memory.  It applies to entries created or
+	// updated after it is set.
+	LazyBounds bool
+
+	// LinearScanThreshold is the number of objects up to which the tree is
+	// kept as a single leaf, which queries scan linearly.  The tree structure
+	// is built as soon as the size exceeds the threshold.  Thresholds up to
+	// MaxChildren have no effect, since such trees fit in a single leaf
+	// anyway.
+	LinearScanThreshold int
+
+	// Epsilon is the tolerance of the comparisons of coordinates made by
+	// SearchIntersect, SearchIntersectIter, SearchIntersectBoxes, Intersects,
+	// ForEachInRect, CountIntersect, SearchContained, SearchContainingPoint
+	// and NearestInRect: objects within Epsilon of the query along every
+	// dimension are matched, which absorbs rounding errors near the boundary
+	// of the query.  It defaults to 0, which compares coordinates exactly.  It
+	// must not be negative.
+	Epsilon float64
+
+	// HalfOpen makes SearchIntersect, SearchIntersectIter,
+	// SearchIntersectBoxes, Intersects, ForEachInRect, CountIntersect,
+	// SearchContainingPoint and NearestInRect treat the bounds of the objects
+	// and of the query as half-open, excluding their upper bound in every
+	// dimension, so that objects merely touching the query, such as adjacent
+	// tiles, don't match.  Along the dimensions where a rectangle has a zero
+	// length, it is the single coordinate of its lower bound.  It defaults to
+	// false, where bounds are closed.
+	HalfOpen bool
+
+	// ReinsertPercent is the percentage of the entries of an overflowing node
+	// that the forced reinsertion of R*-trees removes and inserts again.  The
+	// resulting number of entries is clamped so that at least one entry is
+	// reinserted and at least MinChildren are kept.  NewTreeRStar sets it to
+	// 30, and 0 or less disables forced reinsertion, so that overflowing
+	// nodes are always split.  It has no effect on trees not created by
+	// NewTreeRStar.
+	ReinsertPercent int
+
+	root   *node
+	size   int
+	height int
+
+	// rstar enables the forced reinsertion of the R*-tree, see NewTreeRStar.
+	rstar bool
+
+	// reinsertedLevels records the levels at which entries were already
+	// force reinserted during the current Insert.  It is nil outside of
+	// Insert, which disables forced reinsertion.
+	reinsertedLevels []bool
+
+	// onMutation is called after every mutation if it isn't nil.
+	onMutation func(ev MutationEvent)
 
 	// deleted is a temporary buffer to avoid memory allocations in Delete.
 	// It is just an optimization and not part of the data structure.
@@ -36,7 +215,9 @@ type Rtree struct {
 
 // NewTree returns an Rtree. If the number of objects given on initialization
 // is larger than max, the Rtree will be initialized using the Overlap
-// Minimizing Top-down bulk-loading algorithm.
+// Minimizing Top-down bulk-loading algorithm.  The parameters aren't checked,
+// see NewTreeChecked for their valid ranges, but NewTree panics if some
+// object is invalid, as described for objectBounds.
 func NewTree(dim, min, max int, objs ...Spatial) *Rtree {
 	rt := &Rtree{
 		Dim:         dim,
@@ -49,6 +230,11 @@ func NewTree(dim, min, max int, objs ...Spatial) *Rtree {
 			level:   1,
 		},
 	}
+	for _, obj := range objs {
+		if _, err := objectBounds(dim, obj); err != nil {
+			panic(err)
+		}
+	}
 
 	if len(objs) <= rt.MaxChildren {
 		for _, obj := range objs {
@@ -61,20 +247,355 @@ func NewTree(dim, min, max int, objs ...Spatial) *Rtree {
 	return rt
 }
 
+// NewTreeChecked returns an Rtree like NewTree after checking that its
+// parameters are valid: dim and min must be at least 1, and max must be at
+// least 2 and 2*min-1, so that an overflowing node of max+1 entries can be
+// split in two nodes of at least min entries.  NewTree accepts any
+// parameters, but yields trees that can't be split correctly when they are
+// invalid.
+// NewTreeChecked also returns an error if some object is invalid, as
+// described for objectBounds.
+func NewTreeChecked(dim, min, max int, objs ...Spatial) (*Rtree, error) {
+	if err := checkParams(dim, min, max); err != nil {
+		return nil, err
+	}
+	for _, obj := range objs {
+		if _, err := objectBounds(dim, obj); err != nil {
+			return nil, err
+		}
+	}
+	return NewTree(dim, min, max, objs...), nil
+}
+
+// checkParams returns an error if dim, min and max aren't valid parameters for
+// a tree, as described for NewTreeChecked.
+func checkParams(dim, min, max int) error {
+	switch {
+	case dim < 1:
+		return fmt.Errorf("rtreego: invalid dimension %d, expected at least 1", dim)
+	case min < 1:
+		return fmt.Errorf("rtreego: invalid MinChildren %d, expected at least 1", min)
+	case max < 2:
+		return fmt.Errorf("rtreego: invalid MaxChildren %d, expected at least 2", max)
+	case max < 2*min-1:
+		return fmt.Errorf("rtreego: invalid MaxChildren %d, expected at least 2*MinChildren-1 = %d", max, 2*min-1)
+	}
+	return nil
+}
+
+// objectBounds returns the bounds of obj after checking that obj can be
+// stored in a tree of dimension dim.  It returns an error rather than
+// panicking if obj is nil or if its Bounds method panics, e.g. when
+// dereferencing a nil pointer, a *DimError if the bounds don't have dim
+// dimensions, such as the zero Rect, and an error if they have NaN or
+// infinite coordinates.
+func objectBounds(dim int, obj Spatial) (bb Rect, err error) {
+	if obj == nil {
+		return Rect{}, fmt.Errorf("rtreego: nil object")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			bb, err = Rect{}, fmt.Errorf("rtreego: Bounds of %T panicked: %v", obj, r)
+		}
+	}()
+	bb = obj.Bounds()
+	if len(bb.p) != dim {
+		return Rect{}, &DimError{dim, len(bb.p)}
+	}
+	if err := bb.checkFinite(); err != nil {
+		return Rect{}, err
+	}
+	return bb, nil
+}
+
+// LoadStream returns a tree of the given parameters holding the objects
+// produced by next, which returns false once there are no more objects, packed
+// with the Sort-Tile-Recursive algorithm like Load.  It returns an error if
+// the parameters or the bounds of some object are invalid, as for
+// NewTreeChecked.
+//
+// The objects are sorted in memory, so the resulting tree must fit in memory,
+// but no other copy of them is kept: they are read one at a time into the
+// entries of the tree, which are packed in place into the leaves.  Only the
+// entries slice grows while reading, which may briefly need twice its size.
+func LoadStream(dim, min, max int, next func() (Spatial, bool)) (*Rtree, error) {
+	if err := checkParams(dim, min, max); err != nil {
+		return nil, err
+	}
+	var entries []entry
+	for obj, ok := next(); ok; obj, ok = next() {
+		bb, err := objectBounds(dim, obj)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry{bb: bb, obj: obj})
+	}
+
+	tree := NewTree(dim, min, max)
+	if len(entries) > 0 {
+		tree.pack(entries)
+	}
+	return tree, nil
+}
+
+// Clear removes all objects from the tree, leaving it empty as if it had just
+// been created.  The configuration of the tree, such as Dim, MinChildren and
+// MaxChildren, is kept.  The hook registered with OnMutation is notified of
+// the deletion of every removed object.
+func (tree *Rtree) Clear() {
+	var removed []entry
+	if tree.onMutation != nil {
+		removed = tree.root.leafEntries(nil)
+	}
+	tree.root = &node{
+		entries: []entry{},
+		leaf:    true,
+		level:   1,
+	}
+	tree.size = 0
+	tree.height = 1
+	for _, e := range removed {
+		tree.notify(DeleteMutation, e.obj, e.bb)
+	}
+}
+
+// Clone returns a copy of tree that can be modified independently of it.  The
+// nodes, entries and bounding boxes are copied, while the stored objects are
+// shared by both trees.  The hook registered with OnMutation isn't copied.
+func (tree *Rtree) Clone() *Rtree {
+	clone := *tree
+	clone.onMutation = nil
+	clone.reinsertedLevels = nil
+	clone.deleted = nil
+	clone.root = tree.root.clone(nil)
+	return &clone
+}
+
+// clone returns a deep copy of the subtree n with the given parent.
+func (n *node) clone(parent *node) *node {
+	c := &node{
+		parent:  parent,
+		leaf:    n.leaf,
+		level:   n.level,
+		count:   n.count,
+		entries: make([]entry, len(n.entries)),
+	}
+	for i, e := range n.entries {
+		c.entries[i] = entry{bb: e.bb.clone(), obj: e.obj}
+		if e.child != nil {
+			c.entries[i].child = e.child.clone(c)
+		}
+	}
+	return c
+}
+
+// NewTreeRStar returns an Rtree like NewTree which handles overflowing nodes
+// like an R*-tree: the first time a node overflows at a given level during an
+// Insert, the entries farthest from the center of the node are removed and
+// inserted again from the root instead of splitting the node, see
+// ReinsertPercent.  Nodes are split with RStarSplit, and objects are added to
+// the leaf whose bounding box grows its overlap with its siblings the least.
+// This improves the node utilization and the quality of the tree at the cost
+// of slower inserts.
+//
+// Implemented per Section 4.3 of "The R*-tree: An Efficient and Robust Access
+// Method for Points and Rectangles" by N. Beckmann, H.-P. Kriegel, R. Schneider
+// and B. Seeger, ACM SIGMOD, pages 322-331, 1990.
+func NewTreeRStar(dim, min, max int, objs ...Spatial) *Rtree {
+	rt := NewTree(dim, min, max, objs...)
+	rt.rstar = true
+	rt.ReinsertPercent = 30
+	rt.SplitStrategy = RStarSplit
+	return rt
+}
+
+// NewTreeCanonical returns an Rtree holding objs whose structure depends only
+// on the set of objects and not on the order in which they are given: the
+// same objects always produce the same nodes, entries, and entry order.  This
+// makes trees reproducible across processes, e.g. for caching or diffing.
+// Objects with identical bounds are indistinguishable to the ordering, so
+// their relative placement follows their order in objs.  Like NewTree, it
+// panics if some object is invalid, as described for objectBounds.
+func NewTreeCanonical(dim, min, max int, objs []Spatial) *Rtree {
+	entries := make([]entry, len(objs))
+	for i, obj := range objs {
+		bb, err := objectBounds(dim, obj)
+		if err != nil {
+			panic(err)
+		}
+		entries[i] = entry{bb: bb, obj: obj}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareRects(entries[i].bb, entries[j].bb) < 0
+	})
+
+	sorted := make([]Spatial, len(entries))
+	for i, e := range entries {
+		sorted[i] = e.obj
+	}
+	return NewTree(dim, min, max, sorted...)
+}
+
+// OnMutation registers hook to be called after each successful mutation of
+// tree, replacing any previously registered hook; a nil hook disables the
+// notifications.  Splits are reported while the insertion or deletion causing
+// them is still in progress, before the event for the insertion or deletion
+// itself, so hook must not modify or query the tree.
+func (tree *Rtree) OnMutation(hook func(ev MutationEvent)) {
+	tree.onMutation = hook
+}
+
+// notify reports a mutation to the registered hook.
+func (tree *Rtree) notify(kind MutationKind, obj Spatial, bounds ...Rect) {
+	if tree.onMutation != nil {
+		tree.onMutation(MutationEvent{Kind: kind, Object: obj, Bounds: bounds})
+	}
+}
+
 // Size returns the number of objects currently stored in tree.
 func (tree *Rtree) Size() int {
 	return tree.size
 }
 
+// String returns a multi-line rendering of the tree for debugging.  The first
+// line summarizes the tree's parameters, and each following line describes a
+// node or an entry, indented by its depth below the root.
 func (tree *Rtree) String() string {
-	return "foo"
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rtree{Dim: %d, MinChildren: %d, MaxChildren: %d, Size: %d, Depth: %d}\n",
+		tree.Dim, tree.MinChildren, tree.MaxChildren, tree.size, tree.height)
+	writeNode(&b, tree.root, 1)
+	return b.String()
+}
+
+// writeNode renders n and its subtree into b with the given indentation depth.
+func writeNode(b *strings.Builder, n *node, depth int) {
+	indent := strings.Repeat("  ", depth)
+	kind := "interior"
+	if n.leaf {
+		kind = "leaf"
+	}
+	fmt.Fprintf(b, "%s%s node, level %d, %d entries\n", indent, kind, n.level, len(n.entries))
+	for _, e := range n.entries {
+		if n.leaf {
+			fmt.Fprintf(b, "%s  %v: %v\n", indent, e.bb, e.obj)
+			continue
+		}
+		fmt.Fprintf(b, "%s  %v\n", indent, e.bounds())
+		writeNode(b, e.child, depth+2)
+	}
+}
+
+// Validate checks the structural invariants of the tree and returns an error
+// describing the first violation found, or nil if the tree is valid.  It
+// checks that every node other than the root has between MinChildren and
+// MaxChildren entries, that the bounding box of every interior entry is the
+// smallest rectangle containing its child's entries, that all leaves are at
+// the same depth, that parent pointers are consistent, that leaf entries hold
+// an object, and that the tree holds Size objects.  Validate is intended for
+// tests and debugging.
+func (tree *Rtree) Validate() error {
+	if tree.root.parent != nil {
+		return fmt.Errorf("rtreego: root has a parent")
+	}
+	if tree.root.level != tree.height {
+		return fmt.Errorf("rtreego: root at level %d in a tree of height %d", tree.root.level, tree.height)
+	}
+	if !tree.root.leaf && len(tree.root.entries) > tree.MaxChildren {
+		return fmt.Errorf("rtreego: root has %d entries, more than MaxChildren = %d", len(tree.root.entries), tree.MaxChildren)
+	}
+	size, err := tree.validateNode(tree.root)
+	if err != nil {
+		return err
+	}
+	if size != tree.size {
+		return fmt.Errorf("rtreego: tree holds %d objects but its size is %d", size, tree.size)
+	}
+	return nil
+}
+
+// validateNode checks the invariants of the subtree n and returns the number
+// of objects it holds.
+func (tree *Rtree) validateNode(n *node) (int, error) {
+	if n != tree.root {
+		if len(n.entries) < tree.MinChildren || len(n.entries) > tree.MaxChildren {
+			return 0, fmt.Errorf("rtreego: node at level %d has %d entries, expected between MinChildren = %d and MaxChildren = %d",
+				n.level, len(n.entries), tree.MinChildren, tree.MaxChildren)
+		}
+	}
+	if n.leaf {
+		if n.level != 1 {
+			return 0, fmt.Errorf("rtreego: leaf at level %d, expected all leaves at level 1", n.level)
+		}
+		for i, e := range n.entries {
+			if e.obj == nil || e.child != nil {
+				return 0, fmt.Errorf("rtreego: leaf entry %d %v doesn't hold an object", i, e.bb)
+			}
+			if len(e.bb.p) != tree.Dim {
+				return 0, fmt.Errorf("rtreego: leaf entry %d %v has %d dimensions, expected %d", i, e.bb, len(e.bb.p), tree.Dim)
+			}
+		}
+		if n.count != len(n.entries) {
+			return 0, fmt.Errorf("rtreego: leaf counts %d objects, expected %d", n.count, len(n.entries))
+		}
+		return len(n.entries), nil
+	}
+
+	size := 0
+	for i, e := range n.entries {
+		if e.child == nil || e.obj != nil {
+			return 0, fmt.Errorf("rtreego: interior entry %d at level %d doesn't hold a child", i, n.level)
+		}
+		if e.child.parent != n {
+			return 0, fmt.Errorf("rtreego: child of entry %d at level %d has an inconsistent parent pointer", i, n.level)
+		}
+		if e.child.level != n.level-1 {
+			return 0, fmt.Errorf("rtreego: child of entry %d at level %d is at level %d", i, n.level, e.child.level)
+		}
+		if len(e.child.entries) > 0 {
+			if bb, mbr := e.bounds(), e.child.computeBoundingBox(); !bb.Equal(mbr) {
+				return 0, fmt.Errorf("rtreego: entry %d at level %d has bounding box %v, expected %v", i, n.level, bb, mbr)
+			}
+		}
+		childSize, err := tree.validateNode(e.child)
+		if err != nil {
+			return 0, err
+		}
+		size += childSize
+	}
+	if n.count != size {
+		return 0, fmt.Errorf("rtreego: node at level %d counts %d objects, expected %d", n.level, n.count, size)
+	}
+	return size, nil
 }
 
-// Depth returns the maximum depth of tree.
+// Depth returns the maximum depth of tree.  It is tracked as the tree grows
+// and shrinks, so it doesn't traverse the tree.
 func (tree *Rtree) Depth() int {
 	return tree.height
 }
 
+// IsBalanced reports whether all the leaves of tree are at the same depth,
+// which is Depth.  It traverses the whole tree and is intended for tests and
+// debugging; Validate also checks it along with the other invariants.
+func (tree *Rtree) IsBalanced() bool {
+	return tree.root.leavesAtDepth(tree.height)
+}
+
+// leavesAtDepth reports whether all the leaves below n are depth levels below
+// it, counting n itself.
+func (n *node) leavesAtDepth(depth int) bool {
+	if n.leaf {
+		return depth == 1
+	}
+	for _, e := range n.entries {
+		if !e.child.leavesAtDepth(depth - 1) {
+			return false
+		}
+	}
+	return true
+}
+
 type dimSorter struct {
 	dim  int
 	objs []entry
@@ -92,17 +613,6 @@ func (s *dimSorter) Less(i, j int) bool {
 	return s.objs[i].bb.p[s.dim] < s.objs[j].bb.p[s.dim]
 }
 
-// walkPartitions splits objs into slices of maximum k elements and
-// iterates over these partitions.
-func walkPartitions(k int, objs []entry, iter func(parts []entry)) {
-	n := (len(objs) + k - 1) / k // ceil(len(objs) / k)
-
-	for i := 1; i < n; i++ {
-		iter(objs[(i-1)*k : i*k])
-	}
-	iter(objs[(n-1)*k:])
-}
-
 func sortByDim(dim int, objs []entry) {
 	sort.Sort(&dimSorter{dim, objs})
 }
@@ -146,27 +656,15 @@ func (tree *Rtree) bulkLoad(objs []Spatial) {
 	tree.height = int(h)
 	tree.size = n
 	tree.root = tree.omt(int(h), int(S), entries, int(s))
+	tree.root.recount()
 }
 
 // omt is the recursive part of the Overlap Minimizing Top-loading bulk-
-// load approach. Returns the root node of a subtree.
+// load approach. Returns the root node of a subtree with m children, or, if
+// m is 0, with as many children as possible given that every node below it
+// must hold at least MinChildren entries.
 func (tree *Rtree) omt(level, nSlices int, objs []entry, m int) *node {
-	// if number of objects is less than or equal than max children per leaf,
-	// we need to create a leaf node
-	if len(objs) <= m {
-		// as long as the recursion is not at the leaf, call it again
-		if level > 1 {
-			child := tree.omt(level-1, nSlices, objs, m)
-			n := &node{
-				level: level,
-				entries: []entry{{
-					bb:    child.computeBoundingBox(),
-					child: child,
-				}},
-			}
-			child.parent = n
-			return n
-		}
+	if level == 1 {
 		entries := make([]entry, len(objs))
 		copy(entries, objs)
 		return &node{
@@ -176,46 +674,193 @@ func (tree *Rtree) omt(level, nSlices int, objs []entry, m int) *node {
 		}
 	}
 
+	if m == 0 {
+		// each child needs at least MinChildren^(level-1) objects to
+		// keep all the nodes below it at least MinChildren full, and
+		// can hold at most MaxChildren^(level-1) of them
+		least, most := 1, 1
+		for i := 1; i < level; i++ {
+			least *= tree.MinChildren
+			most *= tree.MaxChildren
+		}
+		m = tree.MaxChildren
+		if k := len(objs) / least; k < m {
+			m = k
+		}
+		if k := (len(objs) + most - 1) / most; k > m {
+			m = k
+		}
+		if m < 1 {
+			m = 1
+		}
+	}
+
 	n := &node{
 		level:   level,
 		entries: make([]entry, 0, m),
 	}
 
-	// maximum node size given at most M nodes at this level
-	k := (len(objs) + m - 1) / m // = ceil(N / M)
-
 	// In the root level, split objs in nSlices. In all other levels,
-	// we use a single slice.
-	vertSize := len(objs)
-	if nSlices > 1 {
-		vertSize = nSlices * k
-	}
+	// we use a single slice.  Each slice is given a share of the m
+	// children, and the objects are spread evenly over the children so
+	// that none of them is left underfull.
+	for i := 0; i < nSlices; i++ {
+		lo, hi := i*m/nSlices, (i+1)*m/nSlices
+		vert := objs[lo*len(objs)/m : hi*len(objs)/m]
 
-	// create sub trees
-	walkPartitions(vertSize, objs, func(vert []entry) {
 		// sort vertical slice by a different dimension on every level
 		sortByDim((tree.height-level+1)%tree.Dim, vert)
 
-		// split slice into groups of size k
-		walkPartitions(k, vert, func(part []entry) {
-			child := tree.omt(level-1, 1, part, tree.MaxChildren)
+		// split slice into its share of groups
+		evenPartitions(hi-lo, vert, func(part []entry) {
+			child := tree.omt(level-1, 1, part, 0)
 			child.parent = n
 
-			n.entries = append(n.entries, entry{
-				bb:    child.computeBoundingBox(),
-				child: child,
-			})
+			n.entries = append(n.entries, tree.childEntry(child))
 		})
-	})
+	}
 	return n
 }
 
+// Load bulk loads objs into the tree using the Sort-Tile-Recursive packing
+// algorithm, which builds the tree bottom-up from nearly full nodes.  Any
+// objects already in the tree are packed together with objs, so Load is much
+// faster than repeated calls to Insert and generally yields a better tree.
+// The result is an ordinary Rtree that can be modified with Insert and Delete.
+//
+// Implemented per "STR: A Simple and Efficient Algorithm for R-Tree Packing"
+// by S. Leutenegger, M. Lopez and J. Edgington, ICDE, pages 497-506, 1997.
+//
+// Load panics, leaving the tree unchanged, if some object is invalid, as
+// described for objectBounds.
+func (tree *Rtree) Load(objs ...Spatial) {
+	if len(objs) == 0 {
+		return
+	}
+
+	entries := tree.root.leafEntries(make([]entry, 0, tree.size+len(objs)))
+	added := make([]entry, len(objs))
+	for i, obj := range objs {
+		bb, err := objectBounds(tree.Dim, obj)
+		if err != nil {
+			panic(err)
+		}
+		added[i] = entry{bb: bb, obj: obj}
+	}
+	tree.pack(append(entries, added...))
+	for _, e := range added {
+		tree.notify(InsertMutation, e.obj, e.bb)
+	}
+}
+
+// Rebuild repacks the objects stored in tree with the Sort-Tile-Recursive
+// algorithm, as Load does, which recompacts a tree fragmented by many inserts
+// and deletes.  The objects and the configuration of the tree are unchanged,
+// so searches return the same objects, and no mutation event is reported.
+func (tree *Rtree) Rebuild() {
+	if tree.size == 0 {
+		return
+	}
+	tree.pack(tree.root.leafEntries(make([]entry, 0, tree.size)))
+}
+
+// pack replaces the nodes of tree by nodes tiled from the leaf entries.
+func (tree *Rtree) pack(entries []entry) {
+	size := len(entries)
+	level := 1
+	nodes := tree.strPack(entries, level, func(entries []entry) *node {
+		return &node{leaf: true, level: level, entries: entries}
+	})
+	for len(nodes) > 1 {
+		level++
+		entries = make([]entry, len(nodes))
+		for i, n := range nodes {
+			entries[i] = tree.childEntry(n)
+		}
+		nodes = tree.strPack(entries, level, func(entries []entry) *node {
+			n := &node{level: level, entries: entries}
+			for _, e := range entries {
+				e.child.parent = n
+			}
+			return n
+		})
+	}
+
+	tree.root = nodes[0]
+	tree.root.recount()
+	tree.height = level
+	tree.size = size
+}
+
+// strPack tiles entries into nodes of at most MaxChildren entries at the
+// given level, built by newNode.  Entries are sorted by the centers of their
+// bounds on the first axis and cut into slices, and each slice is recursively
+// tiled on the following axes.
+func (tree *Rtree) strPack(entries []entry, level int, newNode func(entries []entry) *node) []*node {
+	var nodes []*node
+	var tile func(entries []entry, axis int)
+	tile = func(entries []entry, axis int) {
+		sort.SliceStable(entries, func(i, j int) bool {
+			bi, bj := entries[i].bounds(), entries[j].bounds()
+			return bi.p[axis]+bi.q[axis] < bj.p[axis]+bj.q[axis]
+		})
+
+		// number of nodes needed to hold the entries
+		pages := (len(entries) + tree.MaxChildren - 1) / tree.MaxChildren
+		if axis == tree.Dim-1 || pages <= 1 {
+			// the nodes share the array of entries, capped so that appending
+			// to one of them reallocates it instead of overwriting the next
+			evenPartitions(pages, entries, func(part []entry) {
+				nodes = append(nodes, newNode(slices.Clip(part)))
+			})
+			return
+		}
+
+		// cut into pages^(1/d) slices, where d is the number of axes left
+		slices := int(math.Ceil(math.Pow(float64(pages), 1/float64(tree.Dim-axis))))
+		evenPartitions(slices, entries, func(part []entry) {
+			tile(part, axis+1)
+		})
+	}
+	tile(entries, 0)
+	return nodes
+}
+
+// evenPartitions splits objs into k slices whose lengths differ by at most one
+// and iterates over these partitions, so that the last partition is never left
+// underfull.
+func evenPartitions(k int, objs []entry, iter func(part []entry)) {
+	for i := 0; i < k; i++ {
+		iter(objs[i*len(objs)/k : (i+1)*len(objs)/k])
+	}
+}
+
+// leafEntries appends the entries of all leaves below n to entries.
+func (n *node) leafEntries(entries []entry) []entry {
+	if n.leaf {
+		return append(entries, n.entries...)
+	}
+	for _, e := range n.entries {
+		entries = e.child.leafEntries(entries)
+	}
+	return entries
+}
+
 // node represents a tree node of an Rtree.
 type node struct {
 	parent  *node
 	leaf    bool
 	entries []entry
 	level   int // node depth in the Rtree
+	count   int // number of objects in the subtree
+}
+
+// newNode returns a node at the given level below parent holding entries,
+// counting the objects in its subtree.
+func newNode(parent *node, leaf bool, entries []entry, level int) *node {
+	n := &node{parent: parent, leaf: leaf, entries: entries, level: level}
+	n.recount()
+	return n
 }
 
 func (n *node) String() string {
@@ -231,11 +876,42 @@ type entry struct {
 
 func (e entry) String() string {
 	if e.child != nil {
-		return fmt.Sprintf("entry{bb: %v, child: %v}", e.bb, e.child)
+		return fmt.Sprintf("entry{bb: %v, child: %v}", e.bounds(), e.child)
 	}
 	return fmt.Sprintf("entry{bb: %v, obj: %v}", e.bb, e.obj)
 }
 
+// bounds returns the bounding box of the entry.  Interior entries of trees
+// using LazyBounds don't store it, so it is computed from the child node.
+func (e entry) bounds() Rect {
+	if e.bb.p == nil && e.child != nil {
+		return e.child.computeBoundingBox()
+	}
+	return e.bb
+}
+
+// childEntry returns an entry pointing to the node n.
+func (tree *Rtree) childEntry(n *node) entry {
+	n.recount()
+	if tree.LazyBounds {
+		return entry{child: n}
+	}
+	return entry{bb: n.computeBoundingBox(), child: n}
+}
+
+// refreshEntry updates the bounding box of en, which points to the node n,
+// after the children of n have changed.  It returns false if the bounding
+// box is known to be unchanged.
+func (tree *Rtree) refreshEntry(en *entry, n *node) bool {
+	if tree.LazyBounds {
+		en.bb = Rect{}
+		return true
+	}
+	prevBox := en.bb
+	en.bb = n.computeBoundingBox()
+	return !en.bb.Equal(prevBox)
+}
+
 // Spatial is an interface for objects that can be stored in an Rtree and queried.
 type Spatial interface {
 	Bounds() Rect
@@ -245,85 +921,418 @@ type Spatial interface {
 
 // Insert inserts a spatial object into the tree.  If insertion
 // causes a leaf node to overflow, the tree is rebalanced automatically.
+// Insert returns a *DimError and leaves the tree unchanged if the bounds of
+// obj don't have the dimension of the tree, and an error if they have NaN or
+// infinite coordinates, if obj is nil or if its Bounds method panics.
 //
 // Implemented per Section 3.2 of "R-trees: A Dynamic Index Structure for
 // Spatial Searching" by A. Guttman, Proceedings of ACM SIGMOD, p. 47-57, 1984.
-func (tree *Rtree) Insert(obj Spatial) {
-	e := entry{obj.Bounds(), nil, obj}
-	tree.insert(e, 1)
+func (tree *Rtree) Insert(obj Spatial) error {
+	_, err := tree.insertSpatial(obj)
+	return err
+}
+
+// insertSpatial validates and inserts obj like Insert, and returns the leaf
+// it was placed in as insertObject does.
+func (tree *Rtree) insertSpatial(obj Spatial) (*node, error) {
+	bb, err := objectBounds(tree.Dim, obj)
+	if err != nil {
+		return nil, err
+	}
+	e := entry{bb, nil, obj}
+	leaf := tree.insertObject(e)
+	tree.notify(InsertMutation, obj, e.bb)
+	return leaf, nil
+}
+
+// insertObject adds the leaf entry e to the tree and returns the leaf it was
+// placed in, or nil if the tree was bulk loaded around it.
+func (tree *Rtree) insertObject(e entry) *node {
+	var leaf *node
+	switch {
+	case tree.root.leaf && tree.size < tree.LinearScanThreshold:
+		// small trees are kept in a single leaf
+		tree.root.entries = append(tree.root.entries, e)
+		tree.root.count++
+		leaf = tree.root
+	case tree.root.leaf && len(tree.root.entries) > tree.MaxChildren:
+		// the single leaf of a small tree may exceed MaxChildren, so
+		// bulk load the tree instead of splitting the leaf
+		tree.root.entries = append(tree.root.entries, e)
+		tree.buildLeafRoot()
+	default:
+		if tree.rstar {
+			tree.reinsertedLevels = make([]bool, tree.height+1)
+		}
+		leaf = tree.insert(e, 1)
+		tree.reinsertedLevels = nil
+	}
 	tree.size++
+	return leaf
 }
 
-// insert adds the specified entry to the tree at the specified level.
-func (tree *Rtree) insert(e entry, level int) {
-	leaf := tree.chooseNode(tree.root, e, level)
-	leaf.entries = append(leaf.entries, e)
+// InsertBatch inserts objs into the tree like a sequence of calls to Insert,
+// but adjusts the tree once per affected node rather than once per object:
+// every object is first added to the leaf chosen for it, and overflowing
+// nodes are then repacked as in Load and the bounding boxes refreshed level
+// by level.  The repacking isn't reported as splits to the OnMutation hook.
+// Trees kept in a single leaf and R*-trees insert the objects one at a time.
+// InsertBatch returns an error and inserts nothing if some object is invalid,
+// as for Insert.
+func (tree *Rtree) InsertBatch(objs []Spatial) error {
+	entries := make([]entry, len(objs))
+	for i, obj := range objs {
+		bb, err := objectBounds(tree.Dim, obj)
+		if err != nil {
+			return err
+		}
+		entries[i] = entry{bb, nil, obj}
+	}
 
-	// update parent pointer if necessary
-	if e.child != nil {
-		e.child.parent = leaf
+	if tree.root.leaf || tree.rstar {
+		for _, e := range entries {
+			tree.insertObject(e)
+			tree.notify(InsertMutation, e.obj, e.bb)
+		}
+		return nil
 	}
 
-	// split leaf if overflows
-	var split *node
-	if len(leaf.entries) > tree.MaxChildren {
-		leaf, split = leaf.split(tree.MinChildren)
+	// add the entries to their leaves, recording the affected leaves
+	var dirty []*node
+	seen := make(map[*node]bool)
+	for _, e := range entries {
+		leaf := tree.chooseNode(tree.root, e, 1)
+		leaf.entries = append(leaf.entries, e)
+		leaf.addCount(1)
+		if !seen[leaf] {
+			seen[leaf] = true
+			dirty = append(dirty, leaf)
+		}
 	}
-	root, splitRoot := tree.adjustTree(leaf, split)
-	if splitRoot != nil {
-		oldRoot := root
-		tree.height++
-		tree.root = &node{
-			parent: nil,
-			level:  tree.height,
-			entries: []entry{
-				{bb: oldRoot.computeBoundingBox(), child: oldRoot},
-				{bb: splitRoot.computeBoundingBox(), child: splitRoot},
-			},
+
+	// split and refresh the affected nodes, one level at a time
+	for len(dirty) > 0 {
+		var parents []*node
+		for _, n := range dirty {
+			nodes := tree.splitOverflow(n)
+			if n == tree.root {
+				if len(nodes) > 1 {
+					tree.growRoot(nodes)
+					parents = append(parents, tree.root)
+				}
+				continue
+			}
+			for _, split := range nodes[1:] {
+				split.parent = n.parent
+				n.parent.entries = append(n.parent.entries, tree.childEntry(split))
+			}
+			tree.refreshEntry(n.getEntry(), n)
+			if !seen[n.parent] {
+				seen[n.parent] = true
+				parents = append(parents, n.parent)
+			}
 		}
-		oldRoot.parent = tree.root
-		splitRoot.parent = tree.root
+		dirty = parents
+	}
+
+	tree.size += len(entries)
+	for _, e := range entries {
+		tree.notify(InsertMutation, e.obj, e.bb)
 	}
+	return nil
 }
 
-// chooseNode finds the node at the specified level to which e should be added.
-func (tree *Rtree) chooseNode(n *node, e entry, level int) *node {
-	if n.leaf || n.level == level {
-		return n
+// splitOverflow tiles the entries of the overflowing node n into nodes of at
+// most MaxChildren entries like Load, which is much faster than repeated
+// splits when many entries were added to n, and returns the nodes starting
+// with n itself.
+func (tree *Rtree) splitOverflow(n *node) []*node {
+	if len(n.entries) <= tree.MaxChildren {
+		return []*node{n}
 	}
+	nodes := tree.strPack(n.entries, n.level, func(entries []entry) *node {
+		packed := &node{parent: n.parent, leaf: n.leaf, level: n.level, entries: entries}
+		for _, e := range entries {
+			if e.child != nil {
+				e.child.parent = packed
+			}
+		}
+		return packed
+	})
 
-	// find the entry whose bb needs least enlargement to include obj
-	diff := math.MaxFloat64
-	var chosen entry
-	for _, en := range n.entries {
-		bb := boundingBox(en.bb, e.bb)
-		d := bb.Size() - en.bb.Size()
-		if d < diff || (d == diff && en.bb.Size() < chosen.bb.Size()) {
-			diff = d
-			chosen = en
+	// reuse n for the first node, which keeps its entry in the parent
+	n.entries = nodes[0].entries
+	for _, e := range n.entries {
+		if e.child != nil {
+			e.child.parent = n
 		}
 	}
+	n.recount()
+	nodes[0] = n
+	return nodes
+}
 
-	return tree.chooseNode(chosen.child, e, level)
+// growRoot replaces the root of the tree, which was split into nodes, by a
+// new root holding them.
+func (tree *Rtree) growRoot(nodes []*node) {
+	tree.height++
+	tree.root = &node{level: tree.height}
+	for _, n := range nodes {
+		n.parent = tree.root
+		tree.root.entries = append(tree.root.entries, tree.childEntry(n))
+	}
+	tree.root.recount()
 }
 
-// adjustTree splits overflowing nodes and propagates the changes upwards.
-func (tree *Rtree) adjustTree(n, nn *node) (*node, *node) {
-	// Let the caller handle root adjustments.
-	if n == tree.root {
-		return n, nn
+// buildLeafRoot bulk loads the objects stored in the leaf root of tree.
+func (tree *Rtree) buildLeafRoot() {
+	objs := make([]Spatial, len(tree.root.entries))
+	for i, e := range tree.root.entries {
+		objs[i] = e.obj
 	}
+	size := tree.size
+	tree.bulkLoad(objs)
+	tree.size = size
+}
 
-	// Re-size the bounding box of n to account for lower-level changes.
-	en := n.getEntry()
-	prevBox := en.bb
-	en.bb = n.computeBoundingBox()
+// Merge inserts all objects of other into tree, and returns a *DimError if
+// the trees have different dimensions.  Rather than inserting the objects one
+// at a time, the subtrees of other that satisfy the MinChildren and
+// MaxChildren of tree and fit below its root are copied and inserted whole,
+// which keeps tree balanced.  other is left unchanged, and the objects are
+// shared by both trees.
+func (tree *Rtree) Merge(other *Rtree) error {
+	if other.Dim != tree.Dim {
+		return &DimError{tree.Dim, other.Dim}
+	}
+	if other.size == 0 {
+		return nil
+	}
+	if other == tree {
+		other = tree.Clone()
+	}
+
+	tree.merge(other.root, other.root)
+	if tree.onMutation != nil {
+		for _, e := range other.root.leafEntries(nil) {
+			tree.notify(InsertMutation, e.obj, e.bb)
+		}
+	}
+	return nil
+}
+
+// merge inserts the objects of the subtree n of another tree with the given
+// root, inserting whole the largest subtrees that fit in tree.
+func (tree *Rtree) merge(n, root *node) {
+	if n != root && n.level < tree.height && tree.fits(n) {
+		tree.insert(tree.childEntry(tree.copySubtree(n)), n.level+1)
+		tree.size += n.count
+		return
+	}
+	for _, e := range n.entries {
+		if n.leaf {
+			tree.insertObject(entry{bb: e.bb.clone(), obj: e.obj})
+		} else {
+			tree.merge(e.child, root)
+		}
+	}
+}
+
+// fits reports whether every node of the subtree n has between MinChildren
+// and MaxChildren entries.
+func (tree *Rtree) fits(n *node) bool {
+	if len(n.entries) < tree.MinChildren || len(n.entries) > tree.MaxChildren {
+		return false
+	}
+	if !n.leaf {
+		for _, e := range n.entries {
+			if !tree.fits(e.child) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// copySubtree returns a copy of the subtree n, whose interior entries are
+// built like the ones of tree.
+func (tree *Rtree) copySubtree(n *node) *node {
+	c := &node{leaf: n.leaf, level: n.level, entries: make([]entry, len(n.entries))}
+	for i, e := range n.entries {
+		if n.leaf {
+			c.entries[i] = entry{bb: e.bb.clone(), obj: e.obj}
+			continue
+		}
+		child := tree.copySubtree(e.child)
+		child.parent = c
+		c.entries[i] = tree.childEntry(child)
+	}
+	c.recount()
+	return c
+}
+
+// insert adds the specified entry to the tree at the specified level and
+// returns the node it was placed in, which a forced reinsertion may have
+// moved it out of again.
+func (tree *Rtree) insert(e entry, level int) *node {
+	leaf := tree.chooseNode(tree.root, e, level)
+	leaf.entries = append(leaf.entries, e)
+	leaf.addCount(e.count())
+
+	// update parent pointer if necessary
+	if e.child != nil {
+		e.child.parent = leaf
+	}
+
+	// split leaf if overflows
+	var split *node
+	if len(leaf.entries) > tree.MaxChildren {
+		if tree.shouldReinsert(leaf) {
+			tree.reinsert(leaf)
+			return leaf
+		}
+		leaf, split = tree.splitNode(leaf)
+	}
+	placed := leaf
+	if split != nil && split.holds(e) {
+		placed = split
+	}
+	root, splitRoot := tree.adjustTree(leaf, split)
+	if splitRoot != nil {
+		tree.growRoot([]*node{root, splitRoot})
+	}
+	return placed
+}
+
+// shouldReinsert reports whether the overflowing node n should be handled by
+// forced reinsertion rather than split, which happens once per level and per
+// Insert in R*-trees.  The root is always split.
+func (tree *Rtree) shouldReinsert(n *node) bool {
+	if n == tree.root || n.level >= len(tree.reinsertedLevels) || tree.ReinsertPercent <= 0 {
+		return false
+	}
+	return !tree.reinsertedLevels[n.level]
+}
+
+// reinsert removes the ReinsertPercent of the entries of the overflowing node n whose
+// centers are farthest from the center of n, and inserts them again at the
+// level of n, closest first.
+func (tree *Rtree) reinsert(n *node) {
+	tree.reinsertedLevels[n.level] = true
+
+	center := n.computeBoundingBox().Center()
+	dists := make([]float64, len(n.entries))
+	for i, e := range n.entries {
+		dists[i] = center.dist(e.bounds().Center())
+	}
+	sort.Sort(entrySlice{n.entries, dists})
+
+	p := len(n.entries) * tree.ReinsertPercent / 100
+	if p < 1 {
+		p = 1
+	}
+	if max := len(n.entries) - tree.MinChildren; p > max {
+		p = max
+	}
+	keep := len(n.entries) - p
+	removed := make([]entry, p)
+	copy(removed, n.entries[keep:])
+	n.entries = n.entries[:keep]
+	for _, e := range removed {
+		n.addCount(-e.count())
+	}
+	tree.adjustTree(n, nil)
+
+	for _, e := range removed {
+		tree.insert(e, n.level)
+	}
+}
+
+// chooseNode finds the node at the specified level to which e should be added.
+func (tree *Rtree) chooseNode(n *node, e entry, level int) *node {
+	if n.leaf || n.level == level {
+		return n
+	}
+	if tree.rstar && n.level == 2 {
+		return tree.chooseLeastOverlap(n, e)
+	}
+
+	// find the entry whose bb needs least enlargement to include obj, breaking
+	// ties by the area and then by the enlargement of the margin, which still
+	// discriminates when the bounding boxes are flat
+	diff, marginDiff := math.MaxFloat64, math.MaxFloat64
+	var chosen entry
+	var chosenSize float64
+	ebb := e.bounds()
+	bb := scratchRect(len(ebb.p))
+	for _, en := range n.entries {
+		enbb := en.bounds()
+		unionInto(*bb, enbb, ebb)
+		d, size := bb.Size()-enbb.Size(), enbb.Size()
+		if d > diff || (d == diff && size > chosenSize) {
+			continue
+		}
+		m := bb.Margin() - enbb.Margin()
+		if d < diff || size < chosenSize || m < marginDiff {
+			diff, marginDiff = d, m
+			chosen = en
+			chosenSize = size
+		}
+	}
+	releaseRect(bb)
+
+	return tree.chooseNode(chosen.child, e, level)
+}
+
+// chooseLeastOverlap finds the leaf below n, whose children are leaves, to
+// which e should be added in R*-trees: the one whose bounding box enlarged by
+// e overlaps its siblings the least more than before, breaking ties by the
+// least enlargement and then by the area.  Overlap matters most at this level
+// since leaves are the nodes visited the most by queries.
+//
+// Implemented per Section 4.1 of "The R*-tree: An Efficient and Robust Access
+// Method for Points and Rectangles" by N. Beckmann, H.-P. Kriegel, R. Schneider
+// and B. Seeger, ACM SIGMOD, pages 322-331, 1990.
+func (tree *Rtree) chooseLeastOverlap(n *node, e entry) *node {
+	overlapDiff, diff, chosenSize := math.MaxFloat64, math.MaxFloat64, math.MaxFloat64
+	var chosen *node
+	ebb := e.bounds()
+	bb := scratchRect(len(ebb.p))
+	defer releaseRect(bb)
+	for i, en := range n.entries {
+		enbb := en.bounds()
+		unionInto(*bb, enbb, ebb)
+		o := 0.0
+		for j, sibling := range n.entries {
+			if j != i {
+				sbb := sibling.bounds()
+				o += overlap(*bb, sbb) - overlap(enbb, sbb)
+			}
+		}
+		d, size := bb.Size()-enbb.Size(), enbb.Size()
+		if o < overlapDiff || o == overlapDiff && (d < diff || d == diff && size < chosenSize) {
+			overlapDiff, diff, chosenSize = o, d, size
+			chosen = en.child
+		}
+	}
+	return chosen
+}
+
+// adjustTree splits overflowing nodes and propagates the changes upwards.
+func (tree *Rtree) adjustTree(n, nn *node) (*node, *node) {
+	// Let the caller handle root adjustments.
+	if n == tree.root {
+		return n, nn
+	}
+
+	// Re-size the bounding box of n to account for lower-level changes.
+	changed := tree.refreshEntry(n.getEntry(), n)
 
 	// If nn is nil, then we're just propagating changes upwards.
 	if nn == nil {
 		// Optimize for the case where nothing is changed
 		// to avoid computeBoundingBox which is expensive.
-		if en.bb.Equal(prevBox) {
+		if !changed {
 			return tree.root, nil
 		}
 		return tree.adjustTree(n.parent, nil)
@@ -331,18 +1340,33 @@ func (tree *Rtree) adjustTree(n, nn *node) (*node, *node) {
 
 	// Otherwise, these are two nodes resulting from a split.
 	// n was reused as the "left" node, but we need to add nn to n.parent.
-	enn := entry{nn.computeBoundingBox(), nn, nil}
-	n.parent.entries = append(n.parent.entries, enn)
+	n.parent.entries = append(n.parent.entries, tree.childEntry(nn))
 
 	// If the new entry overflows the parent, split the parent and propagate.
 	if len(n.parent.entries) > tree.MaxChildren {
-		return tree.adjustTree(n.parent.split(tree.MinChildren))
+		return tree.adjustTree(tree.splitNode(n.parent))
 	}
 
 	// Otherwise keep propagating changes upwards.
 	return tree.adjustTree(n.parent, nil)
 }
 
+// holds reports whether e is one of the entries of n.  Leaf entries are told
+// apart by the storage of their bounding boxes, since objects needn't be
+// comparable.
+func (n *node) holds(e entry) bool {
+	for _, f := range n.entries {
+		if e.child != nil {
+			if f.child == e.child {
+				return true
+			}
+		} else if len(f.bb.p) > 0 && len(e.bb.p) > 0 && &f.bb.p[0] == &e.bb.p[0] {
+			return true
+		}
+	}
+	return false
+}
+
 // getEntry returns a pointer to the entry for the node n from n's parent.
 func (n *node) getEntry() *entry {
 	var e *entry
@@ -355,30 +1379,244 @@ func (n *node) getEntry() *entry {
 	return e
 }
 
+// compactMinCap is the capacity of the entries of a node below which compact
+// leaves them in place.
+const compactMinCap = 32
+
+// compact copies the entries of n into a right-sized array once they fill
+// less than a quarter of their capacity, e.g. after many objects were deleted
+// from the single leaf of a tree below its LinearScanThreshold, so that the
+// unused part of the array can be released.  Small arrays are left as they
+// are, since nodes are expected to fluctuate between MinChildren and
+// MaxChildren entries.
+func (n *node) compact() {
+	if c := cap(n.entries); c > compactMinCap && len(n.entries) < c/4 {
+		entries := make([]entry, len(n.entries))
+		copy(entries, n.entries)
+		n.entries = entries
+	}
+}
+
+// recount sets the number of objects in the subtree n from its entries.
+func (n *node) recount() {
+	n.count = 0
+	for _, e := range n.entries {
+		n.count += e.count()
+	}
+}
+
+// addCount adds d to the number of objects in n and its ancestors.
+func (n *node) addCount(d int) {
+	for ; n != nil; n = n.parent {
+		n.count += d
+	}
+}
+
+// count returns the number of objects in the subtree of e, which is 1 for a
+// leaf entry.
+func (e entry) count() int {
+	if e.child == nil {
+		return 1
+	}
+	return e.child.count
+}
+
 // computeBoundingBox finds the MBR of the children of n.
 func (n *node) computeBoundingBox() (bb Rect) {
+	bb = n.entries[0].bounds()
 	if len(n.entries) == 1 {
-		bb = n.entries[0].bb
 		return
 	}
 
-	bb = boundingBox(n.entries[0].bb, n.entries[1].bb)
-	for _, e := range n.entries[2:] {
-		bb = boundingBox(bb, e.bb)
+	bb = Rect{make(Point, len(bb.p)), make(Point, len(bb.p))}
+	n.boundingBoxInto(bb)
+	return
+}
+
+// boundingBoxInto stores the MBR of the children of n in the coordinates of
+// dst, without allocating it.
+func (n *node) boundingBoxInto(dst Rect) {
+	first := n.entries[0].bounds()
+	copy(dst.p, first.p)
+	copy(dst.q, first.q)
+	for _, e := range n.entries[1:] {
+		unionInto(dst, dst, e.bounds())
+	}
+}
+
+// scratchBoundingBox returns the MBR of the children of n in a scratch
+// rectangle, which must be given back with releaseRect.
+func (n *node) scratchBoundingBox(dim int) *Rect {
+	bb := scratchRect(dim)
+	n.boundingBoxInto(*bb)
+	return bb
+}
+
+// splitNode splits the overflowing node n into two siblings according to the
+// Splitter or the SplitStrategy, rebalancing them afterwards if RotateSplits
+// is set.
+func (tree *Rtree) splitNode(n *node) (left, right *node) {
+	if tree.Splitter != nil {
+		left, right = tree.splitWith(tree.Splitter, n)
+	} else {
+		left, right = tree.SplitStrategy.split(n, tree.MinChildren)
+	}
+	if tree.RotateSplits {
+		tree.rotate(left, right)
+	}
+	left.recount()
+	right.recount()
+	if tree.onMutation != nil {
+		tree.notify(SplitMutation, nil, left.computeBoundingBox(), right.computeBoundingBox())
+	}
+	return
+}
+
+// splitWith splits n in the groups chosen by s, reusing n as the left node.
+// It panics with a SplitError if s returns an invalid split, which is a
+// programming error, before modifying n.
+func (tree *Rtree) splitWith(s Splitter, n *node) (left, right *node) {
+	entries := slices.Clone(n.entries)
+	bounds := make([]Rect, len(entries))
+	for i, e := range entries {
+		bounds[i] = e.bounds()
+	}
+	first := s.Split(bounds, tree.MinChildren)
+	if len(first) != len(entries) {
+		panic(SplitError{Entries: len(entries), Assigned: len(first), MinChildren: tree.MinChildren})
+	}
+	k := 0
+	for _, f := range first {
+		if f {
+			k++
+		}
+	}
+	if k < tree.MinChildren || len(first)-k < tree.MinChildren {
+		panic(SplitError{
+			Entries:     len(entries),
+			Assigned:    len(first),
+			First:       k,
+			Second:      len(first) - k,
+			MinChildren: tree.MinChildren,
+		})
+	}
+
+	left = n
+	left.entries = n.entries[:0]
+	right = &node{
+		parent: n.parent,
+		leaf:   n.leaf,
+		level:  n.level,
+	}
+	for i, e := range entries {
+		if first[i] {
+			assign(e, left)
+		} else {
+			assign(e, right)
+		}
 	}
 	return
 }
 
+// rotate moves entries between the sibling nodes left and right as long as
+// every move reduces the overlap of their bounding boxes without increasing
+// their total area.  Moves never make a node underflow or overflow, and at
+// most one move per entry is attempted.
+func (tree *Rtree) rotate(left, right *node) {
+	leftBB, rightBB := scratchRect(tree.Dim), scratchRect(tree.Dim)
+	shrunk, grown := scratchRect(tree.Dim), scratchRect(tree.Dim)
+	defer releaseRect(leftBB, rightBB, shrunk, grown)
+	for moves := len(left.entries) + len(right.entries); moves > 0; moves-- {
+		left.boundingBoxInto(*leftBB)
+		right.boundingBoxInto(*rightBB)
+		best := overlap(*leftBB, *rightBB)
+		if best == 0 {
+			return
+		}
+		area := leftBB.Size() + rightBB.Size()
+
+		var from, to *node
+		idx := -1
+		try := func(src, dst *node, dstBB Rect) {
+			if len(src.entries) <= tree.MinChildren || len(dst.entries) >= tree.MaxChildren {
+				return
+			}
+			for i, e := range src.entries {
+				src.boundingBoxWithoutInto(i, *shrunk)
+				unionInto(*grown, dstBB, e.bounds())
+				if shrunk.Size()+grown.Size() > area {
+					continue
+				}
+				if d := overlap(*shrunk, *grown); d < best {
+					best, from, to, idx = d, src, dst, i
+				}
+			}
+		}
+		try(left, right, *rightBB)
+		try(right, left, *leftBB)
+		if idx < 0 {
+			return
+		}
+
+		e := from.entries[idx]
+		from.entries = append(from.entries[:idx], from.entries[idx+1:]...)
+		assign(e, to)
+	}
+}
+
+// boundingBoxWithoutInto stores the MBR of the children of n except the i-th
+// one in the coordinates of dst.  n must have at least two entries.
+func (n *node) boundingBoxWithoutInto(i int, dst Rect) {
+	first := true
+	for j, e := range n.entries {
+		if j == i {
+			continue
+		}
+		if first {
+			bb := e.bounds()
+			copy(dst.p, bb.p)
+			copy(dst.q, bb.q)
+			first = false
+			continue
+		}
+		unionInto(dst, dst, e.bounds())
+	}
+}
+
 // split splits a node into two groups while attempting to minimize the
 // bounding-box area of the resulting groups.
 func (n *node) split(minGroupSize int) (left, right *node) {
-	// find the initial split
 	l, r := n.pickSeeds()
+	return n.splitFromSeeds(minGroupSize, l, r, pickNext)
+}
+
+// splitLinear splits a node into two groups like split, but picks the seeds
+// and the remaining entries in linear time.
+//
+// Implemented per Section 3.5.3 of "R-trees: A Dynamic Index Structure for
+// Spatial Searching" by A. Guttman, Proceedings of ACM SIGMOD, p. 47-57, 1984.
+func (n *node) splitLinear(minGroupSize int) (left, right *node) {
+	l, r := n.linearPickSeeds()
+	return n.splitFromSeeds(minGroupSize, l, r, func(left, right *node, entries []entry) int {
+		// entries are assigned in any order
+		return 0
+	})
+}
+
+// splitFromSeeds splits a node into two groups grown from the entries at the
+// distinct indices l and r, adding the other entries in the order chosen by
+// pick.
+func (n *node) splitFromSeeds(minGroupSize, l, r int, pick func(left, right *node, entries []entry) int) (left, right *node) {
 	leftSeed, rightSeed := n.entries[l], n.entries[r]
 
-	// get the entries to be divided between left and right
-	remaining := append(n.entries[:l], n.entries[l+1:r]...)
-	remaining = append(remaining, n.entries[r+1:]...)
+	// get the entries to be divided between left and right into a new slice,
+	// since n.entries is reused by left
+	remaining := make([]entry, 0, len(n.entries)-2)
+	for i, e := range n.entries {
+		if i != l && i != r {
+			remaining = append(remaining, e)
+		}
+	}
 
 	// setup the new split nodes, but re-use n as the left node
 	left = n
@@ -400,7 +1638,7 @@ func (n *node) split(minGroupSize int) (left, right *node) {
 
 	// distribute all of n's old entries into left and right.
 	for len(remaining) > 0 {
-		next := pickNext(left, right, remaining)
+		next := pick(left, right, remaining)
 		e := remaining[next]
 
 		if len(remaining)+len(left.entries) <= minGroupSize {
@@ -417,6 +1655,116 @@ func (n *node) split(minGroupSize int) (left, right *node) {
 	return
 }
 
+// splitRStar splits a node into two groups of at least minGroupSize entries.
+// The entries are sorted along the axis for which the groups have the least
+// total margin, and then divided where the groups overlap the least, breaking
+// ties by their total area.
+//
+// Implemented per Section 4.2 of "The R*-tree: An Efficient and Robust Access
+// Method for Points and Rectangles" by N. Beckmann, H.-P. Kriegel, R. Schneider
+// and B. Seeger, ACM SIGMOD, pages 322-331, 1990.
+func (n *node) splitRStar(minGroupSize int) (left, right *node) {
+	if minGroupSize < 1 {
+		minGroupSize = 1
+	}
+	if len(n.entries) < 2*minGroupSize {
+		return n.split(minGroupSize)
+	}
+	entries := make([]entry, len(n.entries))
+	copy(entries, n.entries)
+
+	// choose the split axis
+	axis, minMargin := 0, math.MaxFloat64
+	for i := range entries[0].bounds().p {
+		margin := 0.0
+		for _, upper := range []bool{false, true} {
+			sortEntriesByAxis(entries, i, upper)
+			walkDistributions(entries, minGroupSize, func(k int, l, r Rect) {
+				margin += l.Margin() + r.Margin()
+			})
+		}
+		if margin < minMargin {
+			axis, minMargin = i, margin
+		}
+	}
+
+	// choose the split index along that axis
+	var best []entry
+	bestK := 0
+	minOverlap, minArea := math.MaxFloat64, math.MaxFloat64
+	for _, upper := range []bool{false, true} {
+		sortEntriesByAxis(entries, axis, upper)
+		walkDistributions(entries, minGroupSize, func(k int, l, r Rect) {
+			o, a := overlap(l, r), l.Size()+r.Size()
+			if o < minOverlap || (o == minOverlap && a < minArea) {
+				minOverlap, minArea, bestK = o, a, k
+				best = append(best[:0], entries...)
+			}
+		})
+	}
+
+	// setup the new split nodes, but re-use n as the left node
+	left = n
+	left.entries = n.entries[:0]
+	right = &node{
+		parent: n.parent,
+		leaf:   n.leaf,
+		level:  n.level,
+	}
+	for _, e := range best[:bestK] {
+		assign(e, left)
+	}
+	for _, e := range best[bestK:] {
+		assign(e, right)
+	}
+	return
+}
+
+// sortEntriesByAxis sorts entries by the lower or upper sides of their bounds
+// along axis.
+func sortEntriesByAxis(entries []entry, axis int, upper bool) {
+	side := func(e entry) float64 {
+		if upper {
+			return e.bounds().q[axis]
+		}
+		return e.bounds().p[axis]
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return side(entries[i]) < side(entries[j])
+	})
+}
+
+// walkDistributions iterates over the ways of dividing entries into a prefix
+// and a suffix of at least minGroupSize entries, passing the length k of the
+// prefix and the bounding boxes of both groups.
+func walkDistributions(entries []entry, minGroupSize int, iter func(k int, l, r Rect)) {
+	// suffixes[i] is the bounding box of entries[i:], all of them sharing a
+	// single array of coordinates
+	last := entries[len(entries)-1].bounds()
+	dim := len(last.p)
+	suffixes := make([]Rect, len(entries))
+	coords := make([]float64, 2*dim*len(entries))
+	suffixes[len(entries)-1] = last
+	for i := len(entries) - 2; i >= minGroupSize; i-- {
+		c := coords[2*dim*i:]
+		suffixes[i] = Rect{c[:dim:dim], c[dim : 2*dim : 2*dim]}
+		unionInto(suffixes[i], entries[i].bounds(), suffixes[i+1])
+	}
+
+	prefix := scratchRect(dim)
+	defer releaseRect(prefix)
+	first := entries[0].bounds()
+	copy(prefix.p, first.p)
+	copy(prefix.q, first.q)
+	for i := 1; i < minGroupSize; i++ {
+		unionInto(*prefix, *prefix, entries[i].bounds())
+	}
+	for k := minGroupSize; k <= len(entries)-minGroupSize; k++ {
+		iter(k, *prefix, suffixes[k])
+		unionInto(*prefix, *prefix, entries[k].bounds())
+	}
+}
+
 // getAllBoundingBoxes traverses tree populating slice of bounding boxes of non-leaf nodes.
 func (n *node) getAllBoundingBoxes() []Rect {
 	var rects []Rect
@@ -427,7 +1775,7 @@ func (n *node) getAllBoundingBoxes() []Rect {
 		if e.child == nil {
 			return rects
 		}
-		rectsInter := append(e.child.getAllBoundingBoxes(), e.bb)
+		rectsInter := append(e.child.getAllBoundingBoxes(), e.bounds())
 		rects = append(rects, rectsInter...)
 	}
 	return rects
@@ -441,15 +1789,25 @@ func assign(e entry, group *node) {
 }
 
 // assignGroup chooses one of two groups to which a node should be added.
+// When every criterion ties, e is added to the left group, so that splits
+// only depend on the entries and their order.
 func assignGroup(e entry, left, right *node) {
-	leftBB := left.computeBoundingBox()
-	rightBB := right.computeBoundingBox()
-	leftEnlarged := boundingBox(leftBB, e.bb)
-	rightEnlarged := boundingBox(rightBB, e.bb)
-
-	// first, choose the group that needs the least enlargement
+	ebb := e.bounds()
+	dim := len(ebb.p)
+	leftBB, rightBB := left.scratchBoundingBox(dim), right.scratchBoundingBox(dim)
+	leftEnlarged, rightEnlarged := scratchRect(dim), scratchRect(dim)
+	defer releaseRect(leftBB, rightBB, leftEnlarged, rightEnlarged)
+	unionInto(*leftEnlarged, *leftBB, ebb)
+	unionInto(*rightEnlarged, *rightBB, ebb)
+
+	// first, choose the group that needs the least enlargement, measured by
+	// the margin if the areas don't tell, e.g. when the entries are points
 	leftDiff := leftEnlarged.Size() - leftBB.Size()
 	rightDiff := rightEnlarged.Size() - rightBB.Size()
+	if leftDiff == rightDiff {
+		leftDiff = leftEnlarged.Margin() - leftBB.Margin()
+		rightDiff = rightEnlarged.Margin() - rightBB.Margin()
+	}
 	if diff := leftDiff - rightDiff; diff < 0 {
 		assign(e, left)
 		return
@@ -467,7 +1825,7 @@ func assignGroup(e entry, left, right *node) {
 		return
 	}
 
-	// next, choose the group with fewer entries
+	// next, choose the group with fewer entries, and finally the left one
 	if diff := len(left.entries) - len(right.entries); diff <= 0 {
 		assign(e, left)
 		return
@@ -475,15 +1833,28 @@ func assignGroup(e entry, left, right *node) {
 	assign(e, right)
 }
 
-// pickSeeds chooses two child entries of n to start a split.
+// pickSeeds chooses two child entries of n to start a split.  The wasted
+// space of a pair is measured by area, and then by margin to break ties, such
+// as between points whose bounding boxes are all flat.  Pairs wasting the same
+// space and margin are broken by index, in favor of the first pair found, so
+// that the same entries always yield the same seeds.
 func (n *node) pickSeeds() (int, int) {
 	left, right := 0, 1
-	maxWastedSpace := -1.0
+	maxWastedSpace, maxWastedMargin := -1.0, -1.0
+	bb := scratchRect(len(n.entries[0].bounds().p))
+	defer releaseRect(bb)
 	for i, e1 := range n.entries {
+		bb1 := e1.bounds()
 		for j, e2 := range n.entries[i+1:] {
-			d := boundingBox(e1.bb, e2.bb).Size() - e1.bb.Size() - e2.bb.Size()
-			if d > maxWastedSpace {
-				maxWastedSpace = d
+			bb2 := e2.bounds()
+			unionInto(*bb, bb1, bb2)
+			d := bb.Size() - bb1.Size() - bb2.Size()
+			if d < maxWastedSpace {
+				continue
+			}
+			m := bb.Margin() - bb1.Margin() - bb2.Margin()
+			if d > maxWastedSpace || m > maxWastedMargin {
+				maxWastedSpace, maxWastedMargin = d, m
 				left, right = i, j+i+1
 			}
 		}
@@ -491,17 +1862,80 @@ func (n *node) pickSeeds() (int, int) {
 	return left, right
 }
 
-// pickNext chooses an entry to be added to an entry group.
+// linearPickSeeds chooses two child entries of n to start a split in linear
+// time.  Along every axis, it finds the entry with the highest low side and
+// the entry with the lowest high side, and picks the pair whose separation
+// relative to the extent of all entries along the axis is the greatest.
+func (n *node) linearPickSeeds() (int, int) {
+	left, right := 0, 1
+	maxSeparation := math.Inf(-1)
+	for axis := range n.entries[0].bounds().p {
+		highLow, lowHigh := 0, -1
+		highestLow, lowestHigh := math.Inf(-1), math.Inf(1)
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for i, e := range n.entries {
+			bb := e.bounds()
+			if bb.p[axis] > highestLow {
+				highLow, highestLow = i, bb.p[axis]
+			}
+			lo, hi = math.Min(lo, bb.p[axis]), math.Max(hi, bb.q[axis])
+		}
+		for i, e := range n.entries {
+			if q := e.bounds().q[axis]; i != highLow && q < lowestHigh {
+				lowHigh, lowestHigh = i, q
+			}
+		}
+
+		separation := highestLow - lowestHigh
+		if width := hi - lo; width > 0 {
+			separation /= width
+		}
+		if separation > maxSeparation {
+			maxSeparation = separation
+			left, right = highLow, lowHigh
+		}
+	}
+	if left > right {
+		left, right = right, left
+	}
+	return left, right
+}
+
+// pickNext chooses an entry to be added to an entry group: the one with the
+// greatest preference for one group, measured by area and then by margin to
+// break ties.  Among entries with the same preference, the one enlarging the
+// group it prefers the least, by area and then by margin, is chosen, which
+// keeps the groups compact when many entries tie, as on regular grids.
+// Remaining ties are broken by index, in favor of the first entry.
 func pickNext(left, right *node, entries []entry) (next int) {
-	maxDiff := -1.0
-	leftBB := left.computeBoundingBox()
-	rightBB := right.computeBoundingBox()
+	maxDiff, maxMarginDiff := -1.0, -1.0
+	minGrowth, minMarginGrowth := math.Inf(1), math.Inf(1)
+	dim := len(entries[0].bounds().p)
+	leftBB, rightBB := left.scratchBoundingBox(dim), right.scratchBoundingBox(dim)
+	leftEnlarged, rightEnlarged := scratchRect(dim), scratchRect(dim)
+	defer releaseRect(leftBB, rightBB, leftEnlarged, rightEnlarged)
 	for i, e := range entries {
-		d1 := boundingBox(leftBB, e.bb).Size() - leftBB.Size()
-		d2 := boundingBox(rightBB, e.bb).Size() - rightBB.Size()
+		ebb := e.bounds()
+		unionInto(*leftEnlarged, *leftBB, ebb)
+		unionInto(*rightEnlarged, *rightBB, ebb)
+		d1 := leftEnlarged.Size() - leftBB.Size()
+		d2 := rightEnlarged.Size() - rightBB.Size()
 		d := math.Abs(d1 - d2)
-		if d > maxDiff {
-			maxDiff = d
+		if d < maxDiff {
+			continue
+		}
+		m1 := leftEnlarged.Margin() - leftBB.Margin()
+		m2 := rightEnlarged.Margin() - rightBB.Margin()
+		m := math.Abs(m1 - m2)
+		growth, marginGrowth := math.Min(d1, d2), math.Min(m1, m2)
+		if cmp.Or(
+			cmp.Compare(d, maxDiff),
+			cmp.Compare(m, maxMarginDiff),
+			cmp.Compare(minGrowth, growth),
+			cmp.Compare(minMarginGrowth, marginGrowth),
+		) > 0 {
+			maxDiff, maxMarginDiff = d, m
+			minGrowth, minMarginGrowth = growth, marginGrowth
 			next = i
 		}
 	}
@@ -512,7 +1946,9 @@ func pickNext(left, right *node, entries []entry) (next int) {
 
 // Delete removes an object from the tree.  If the object is not found, returns
 // false, otherwise returns true. Uses the default comparator when checking
-// equality.
+// equality, so of several objects stored with identical bounds, only obj
+// itself is removed: every leaf whose bounding box contains the bounds of obj
+// is searched until it is found.
 //
 // Implemented per Section 3.3 of "R-trees: A Dynamic Index Structure for
 // Spatial Searching" by A. Guttman, Proceedings of ACM SIGMOD, p. 47-57, 1984.
@@ -523,61 +1959,303 @@ func (tree *Rtree) Delete(obj Spatial) bool {
 // DeleteWithComparator removes an object from the tree using a custom
 // comparator for evaluating equalness. This is useful when you want to remove
 // an object from a tree but don't have a pointer to the original object
-// anymore.
+// anymore, e.g. after deserializing it.  The leaf holding obj is searched by
+// its bounds, so cmp is only called on stored objects whose bounds contain
+// the bounds of obj.  DeleteWithComparator returns false if obj is invalid as
+// for Insert, such as nil.
 func (tree *Rtree) DeleteWithComparator(obj Spatial, cmp Comparator) bool {
-	n := tree.findLeaf(tree.root, obj, cmp)
+	n, ind := tree.locate(obj, cmp)
 	if n == nil {
 		return false
 	}
 
-	ind := -1
-	for i, e := range n.entries {
-		if cmp(e.obj, obj) {
-			ind = i
-		}
+	deleted := tree.removeObject(n, ind)
+	tree.notify(DeleteMutation, deleted.obj, deleted.bb)
+
+	return true
+}
+
+// Contains reports whether obj is stored in the tree, matching it like
+// Delete: the leaves whose bounding boxes contain the bounds of obj are
+// searched for obj itself, so an object equal to obj but stored separately
+// isn't matched.  Checking Contains before Insert avoids storing an object
+// twice.
+func (tree *Rtree) Contains(obj Spatial) bool {
+	n, _ := tree.locate(obj, defaultComparator)
+	return n != nil
+}
+
+// locate returns the leaf holding obj according to cmp and the index of obj
+// in it, or nil if obj isn't found or is invalid as for Insert.
+func (tree *Rtree) locate(obj Spatial, cmp Comparator) (*node, int) {
+	bb, err := objectBounds(tree.Dim, obj)
+	if err != nil {
+		// obj can't have been inserted
+		return nil, -1
+	}
+	n := tree.findLeafBounds(tree.root, obj, bb, cmp)
+	if n == nil {
+		return nil, -1
 	}
+	ind := n.indexOf(obj, bb, cmp)
 	if ind < 0 {
-		return false
+		return nil, -1
 	}
+	return n, ind
+}
 
-	n.entries = append(n.entries[:ind], n.entries[ind+1:]...)
+// Entry is a handle to an object inserted with InsertHandle, which lets
+// DeleteHandle remove it without searching the tree.
+type Entry struct {
+	obj     Spatial
+	leaf    *node // leaf holding obj when it was last located
+	deleted bool
+}
 
-	tree.condenseTree(n)
-	tree.size--
+// Object returns the object referenced by the handle.
+func (h *Entry) Object() Spatial {
+	return h.obj
+}
 
-	if !tree.root.leaf && len(tree.root.entries) == 1 {
-		tree.root = tree.root.entries[0].child
+// InsertHandle inserts obj into the tree like Insert and returns a handle to
+// it for DeleteHandle.  The handle records the leaf holding obj, so it stays
+// valid across any later change to the tree, but it only saves the search
+// while obj remains in that leaf: once obj is moved by a split, a
+// reinsertion, an Update or a rebuild of the tree, DeleteHandle falls back to
+// searching obj like Delete.
+func (tree *Rtree) InsertHandle(obj Spatial) (*Entry, error) {
+	leaf, err := tree.insertSpatial(obj)
+	if err != nil {
+		return nil, err
 	}
-
-	tree.height = tree.root.level
-
-	return true
+	return &Entry{obj: obj, leaf: leaf}, nil
 }
 
-// findLeaf finds the leaf node containing obj.
-func (tree *Rtree) findLeaf(n *node, obj Spatial, cmp Comparator) *node {
-	if n.leaf {
-		return n
+// DeleteHandle removes the object referenced by h, inserted with
+// InsertHandle, and reports whether it was found.  It returns false if the
+// object was already removed, through h or otherwise.  The object is looked
+// up in the leaf recorded in h, and searched like Delete does if it is no
+// longer there.
+func (tree *Rtree) DeleteHandle(h *Entry) bool {
+	if h == nil || h.deleted {
+		return false
+	}
+
+	n, ind := h.leaf, -1
+	if n != nil && tree.attached(n) {
+		ind = slices.IndexFunc(n.entries, func(e entry) bool { return defaultComparator(e.obj, h.obj) })
+	}
+	if ind < 0 {
+		if n = tree.findLeaf(tree.root, h.obj, defaultComparator); n == nil {
+			return false
+		}
+		if ind = n.indexOf(h.obj, h.obj.Bounds(), defaultComparator); ind < 0 {
+			return false
+		}
+	}
+
+	deleted := tree.removeObject(n, ind)
+	h.leaf, h.deleted = nil, true
+	tree.notify(DeleteMutation, deleted.obj, deleted.bb)
+	return true
+}
+
+// attached reports whether the leaf n is still part of tree, i.e. whether
+// following its parents leads to the root through their entries.
+func (tree *Rtree) attached(n *node) bool {
+	if !n.leaf {
+		return false
+	}
+	for n != tree.root {
+		if n.parent == nil || n.getEntry() == nil {
+			return false
+		}
+		n = n.parent
+	}
+	return true
+}
+
+// DeleteMatching removes all objects for which pred returns true and returns
+// how many were removed.  Matching objects are removed from their leaves in a
+// single traversal of the tree, which is then condensed once, rather than
+// once per object as with Delete.
+func (tree *Rtree) DeleteMatching(pred func(obj Spatial) bool) int {
+	var removed []entry
+	tree.deleted = tree.deleted[:0]
+	tree.deleteMatching(tree.root, pred, &removed)
+	if len(removed) == 0 {
+		return 0
+	}
+
+	sort.SliceStable(tree.deleted, func(i, j int) bool {
+		return tree.deleted[i].level < tree.deleted[j].level
+	})
+	tree.reinsertOrphans()
+	tree.size -= len(removed)
+	tree.shortenRoot()
+
+	for _, e := range removed {
+		tree.notify(DeleteMutation, e.obj, e.bb)
+	}
+	return len(removed)
+}
+
+// deleteMatching removes the objects matching pred from the subtree n,
+// appending them to removed, and the children of n left underfull to
+// tree.deleted.  It reports whether the subtree n was changed.
+func (tree *Rtree) deleteMatching(n *node, pred func(obj Spatial) bool, removed *[]entry) bool {
+	kept := n.entries[:0]
+	changed := false
+	for _, e := range n.entries {
+		switch {
+		case n.leaf && pred(e.obj):
+			*removed = append(*removed, e)
+			changed = true
+			continue
+		case !n.leaf && tree.deleteMatching(e.child, pred, removed):
+			changed = true
+			if len(e.child.entries) < tree.MinChildren {
+				// only keep the child for reinsertion if it still has children
+				if len(e.child.entries) > 0 {
+					tree.deleted = append(tree.deleted, e.child)
+				}
+				continue
+			}
+			tree.refreshEntry(&e, e.child)
+		}
+		kept = append(kept, e)
+	}
+	clear(n.entries[len(kept):])
+	n.entries = kept
+	n.compact()
+	if changed {
+		n.recount()
+	}
+	return changed
+}
+
+// removeObject removes the entry at index ind from the leaf n, condensing
+// the tree afterwards, and returns it.
+func (tree *Rtree) removeObject(n *node, ind int) entry {
+	deleted := n.entries[ind]
+	l := len(n.entries)
+	copy(n.entries[ind:], n.entries[ind+1:])
+	n.entries[l-1] = entry{}
+	n.entries = n.entries[:l-1]
+	n.compact()
+	n.addCount(-1)
+
+	tree.condenseTree(n)
+	tree.size--
+	tree.shortenRoot()
+	return deleted
+}
+
+// shortenRoot removes the interior roots left with a single child or with no
+// child at all after deletions.
+func (tree *Rtree) shortenRoot() {
+	for !tree.root.leaf && len(tree.root.entries) == 1 {
+		tree.root = tree.root.entries[0].child
+	}
+	if !tree.root.leaf && len(tree.root.entries) == 0 {
+		// the last object was deleted, leaving only empty interior nodes
+		tree.root = &node{
+			entries: []entry{},
+			leaf:    true,
+			level:   1,
+		}
+	}
+
+	tree.root.parent = nil
+	tree.height = tree.root.level
+}
+
+// Update changes the bounds under which obj is stored to newBounds, and
+// reports whether obj was found.  obj is looked up by its bounds, so Update
+// must be called while obj.Bounds() still returns its previous bounds, and
+// obj must be changed afterwards so that it returns newBounds.  If the leaf
+// holding obj still contains newBounds, the bounding boxes are adjusted in
+// place; otherwise obj is removed and inserted again.  Update returns a
+// *DimError if newBounds doesn't have the dimension of the tree, such as the
+// zero Rect, and an error if it has NaN or infinite coordinates, or if obj is
+// invalid as for Insert.
+func (tree *Rtree) Update(obj Spatial, newBounds Rect) (bool, error) {
+	if len(newBounds.p) != tree.Dim {
+		return false, &DimError{tree.Dim, len(newBounds.p)}
+	}
+	if err := newBounds.checkFinite(); err != nil {
+		return false, err
+	}
+	bb, err := objectBounds(tree.Dim, obj)
+	if err != nil {
+		return false, err
+	}
+
+	n := tree.findLeafBounds(tree.root, obj, bb, defaultComparator)
+	if n == nil {
+		return false, nil
+	}
+	ind := n.indexOf(obj, bb, defaultComparator)
+	if ind < 0 {
+		return false, nil
+	}
+
+	oldBounds := n.entries[ind].bb
+	if n == tree.root || n.getEntry().bounds().ContainsRect(newBounds) {
+		n.entries[ind].bb = newBounds
+		tree.adjustTree(n, nil)
+	} else {
+		tree.removeObject(n, ind)
+		tree.insertObject(entry{newBounds, nil, obj})
+	}
+	tree.notify(UpdateMutation, obj, oldBounds, newBounds)
+
+	return true, nil
+}
+
+// findLeaf finds the leaf node containing obj.
+func (tree *Rtree) findLeaf(n *node, obj Spatial, cmp Comparator) *node {
+	return tree.findLeafBounds(n, obj, obj.Bounds(), cmp)
+}
+
+func (tree *Rtree) findLeafBounds(n *node, obj Spatial, bb Rect, cmp Comparator) *node {
+	if n.leaf {
+		return n
 	}
 	// if not leaf, search all candidate subtrees
 	for _, e := range n.entries {
-		if e.bb.containsRect(obj.Bounds()) {
-			leaf := tree.findLeaf(e.child, obj, cmp)
+		if e.bounds().ContainsRect(bb) {
+			leaf := tree.findLeafBounds(e.child, obj, bb, cmp)
 			if leaf == nil {
 				continue
 			}
 			// check if the leaf actually contains the object
-			for _, leafEntry := range leaf.entries {
-				if cmp(leafEntry.obj, obj) {
-					return leaf
-				}
+			if leaf.indexOf(obj, bb, cmp) >= 0 {
+				return leaf
 			}
 		}
 	}
 	return nil
 }
 
+// indexOf returns the index of the entry of the leaf n holding obj according
+// to cmp, or -1 if there is none.  Only entries whose bounds contain bb, the
+// bounds of obj, are compared.
+func (n *node) indexOf(obj Spatial, bb Rect, cmp Comparator) int {
+	for i, e := range n.entries {
+		if e.bb.ContainsRect(bb) && cmp(e.obj, obj) {
+			return i
+		}
+	}
+	return -1
+}
+
 // condenseTree deletes underflowing nodes and propagates the changes upwards.
+// The entries of the deleted nodes are inserted again at their own level:
+// objects into leaves and subtrees into interior nodes.
+//
+// Implemented per Section 3.3 of "R-trees: A Dynamic Index Structure for
+// Spatial Searching" by A. Guttman, Proceedings of ACM SIGMOD, p. 47-57, 1984.
 func (tree *Rtree) condenseTree(n *node) {
 	// reset the deleted buffer
 	tree.deleted = tree.deleted[:0]
@@ -597,7 +2275,10 @@ func (tree *Rtree) condenseTree(n *node) {
 			}
 			l := len(n.parent.entries)
 			n.parent.entries[idx] = n.parent.entries[l-1]
+			n.parent.entries[l-1] = entry{}
 			n.parent.entries = n.parent.entries[:l-1]
+			n.parent.compact()
+			n.parent.addCount(-n.count)
 
 			// only add n to deleted if it still has children
 			if len(n.entries) > 0 {
@@ -605,11 +2286,7 @@ func (tree *Rtree) condenseTree(n *node) {
 			}
 		} else {
 			// just a child entry deletion, no underflow
-			en := n.getEntry()
-			prevBox := en.bb
-			en.bb = n.computeBoundingBox()
-
-			if en.bb.Equal(prevBox) {
+			if !tree.refreshEntry(n.getEntry(), n) {
 				// Optimize for the case where nothing is changed
 				// to avoid computeBoundingBox which is expensive.
 				break
@@ -617,27 +2294,96 @@ func (tree *Rtree) condenseTree(n *node) {
 		}
 		n = n.parent
 	}
+	tree.reinsertOrphans()
+}
 
-	for i := len(tree.deleted) - 1; i >= 0; i-- {
-		n := tree.deleted[i]
-		// reinsert entry so that it will remain at the same level as before
-		e := entry{n.computeBoundingBox(), n, nil}
-		tree.insert(e, n.level+1)
+// reinsertOrphans inserts the entries of the nodes removed from the tree,
+// which are held in tree.deleted by increasing level, at the level they were
+// drawn from, highest first, so that the tree remains balanced.
+func (tree *Rtree) reinsertOrphans() {
+	// If the root lost all its children, the highest removed node takes its
+	// place, since the orphaned entries need nodes at their level.
+	deleted := tree.deleted
+	if !tree.root.leaf && len(tree.root.entries) == 0 && len(deleted) > 0 {
+		n := deleted[len(deleted)-1]
+		deleted = deleted[:len(deleted)-1]
+		n.parent = nil
+		tree.root = n
+		tree.height = n.level
+	}
+
+	for i := len(deleted) - 1; i >= 0; i-- {
+		n := deleted[i]
+		for _, e := range n.entries {
+			tree.insert(e, n.level)
+		}
 	}
 }
 
 // Searching
 
-// SearchIntersect returns all objects that intersect the specified rectangle.
+// SearchIntersect returns all objects that intersect the specified rectangle,
+// including objects that merely touch its boundary.  The result is an empty,
+// non-nil slice if nothing matches.
 // Implemented per Section 3.1 of "R-trees: A Dynamic Index Structure for
 // Spatial Searching" by A. Guttman, Proceedings of ACM SIGMOD, p. 47-57, 1984.
 func (tree *Rtree) SearchIntersect(bb Rect, filters ...Filter) []Spatial {
-	return tree.searchIntersect([]Spatial{}, tree.root, bb, filters)
+	tree.checkDim(len(bb.p))
+	results, _ := tree.searchIntersect([]Spatial{}, tree.root, tree.tolerant(bb), filters)
+	return results
+}
+
+// SearchIntersectIter returns an iterator over the objects that intersect the
+// specified rectangle, in the same order as SearchIntersect, without
+// collecting them in a slice.  The tree is searched lazily as the iterator is
+// consumed, and breaking out of the loop stops the search.  The filters are
+// applied to the objects yielded so far.  The tree must not be modified
+// while iterating.
+func (tree *Rtree) SearchIntersectIter(bb Rect, filters ...Filter) iter.Seq[Spatial] {
+	tree.checkDim(len(bb.p))
+	return func(yield func(Spatial) bool) {
+		var results []Spatial
+		tree.searchIntersectIter(tree.root, tree.tolerant(bb), filters, &results, yield)
+	}
+}
+
+// searchIntersectIter yields the objects of the subtree n intersecting bb,
+// appending them to results for the filters, and reports whether the search
+// must stop.
+func (tree *Rtree) searchIntersectIter(n *node, bb Rect, filters []Filter, results *[]Spatial, yield func(Spatial) bool) bool {
+	for _, e := range n.entries {
+		if !tree.overlaps(e.bounds(), bb, n.leaf) {
+			continue
+		}
+
+		if !n.leaf {
+			if tree.searchIntersectIter(e.child, bb, filters, results, yield) {
+				return true
+			}
+			continue
+		}
+
+		refuse, abort := applyFilters(*results, e.obj, filters)
+		if !refuse {
+			if len(filters) > 0 {
+				*results = append(*results, e.obj)
+			}
+			if !yield(e.obj) {
+				return true
+			}
+		}
+
+		if abort {
+			return true
+		}
+	}
+	return false
 }
 
 // SearchIntersectWithLimit is similar to SearchIntersect, but returns
-// immediately when the first k results are found. A negative k behaves exactly
-// like SearchIntersect and returns all the results.
+// immediately when the first k results are found, without descending into
+// the remaining subtrees. A negative k behaves exactly like SearchIntersect
+// and returns all the results.
 //
 // Kept for backwards compatibility, please use SearchIntersect with a
 // LimitFilter.
@@ -650,14 +2396,174 @@ func (tree *Rtree) SearchIntersectWithLimit(k int, bb Rect) []Spatial {
 	return tree.SearchIntersect(bb, LimitFilter(k))
 }
 
-func (tree *Rtree) searchIntersect(results []Spatial, n *node, bb Rect, filters []Filter) []Spatial {
+// SearchIntersectWithFilter is similar to SearchIntersect, but only returns
+// the objects for which filter returns true.  filter is only called on stored
+// objects intersecting bb, never on interior entries.
+func (tree *Rtree) SearchIntersectWithFilter(bb Rect, filter func(obj Spatial) bool) []Spatial {
+	return tree.SearchIntersect(bb, func(results []Spatial, object Spatial) (refuse, abort bool) {
+		return !filter(object), false
+	})
+}
+
+// Intersects reports whether any object intersects the specified rectangle,
+// returning as soon as the first one accepted by the filters is found.
+func (tree *Rtree) Intersects(bb Rect, filters ...Filter) bool {
+	for range tree.SearchIntersectIter(bb, filters...) {
+		return true
+	}
+	return false
+}
+
+// ForEachInRect calls fn for each object that intersects the specified
+// rectangle, in the same order as SearchIntersect, and stops as soon as fn
+// returns false.  No slice of results is allocated, which suits aggregating
+// the objects of a region.  fn must not modify the tree.
+func (tree *Rtree) ForEachInRect(bb Rect, fn func(obj Spatial) bool) {
+	tree.checkDim(len(bb.p))
+	tree.forEachInRect(tree.root, tree.tolerant(bb), func(e entry) bool { return fn(e.obj) })
+}
+
+// ReduceIntersect folds fn over the objects of tree that intersect bb, as
+// found by ForEachInRect, starting from init, and returns the final
+// accumulator.  It computes aggregates over a region, such as sums, minimums
+// or bounding boxes, in a single pass without collecting the objects in a
+// slice.  ReduceIntersect is a function rather than a method of Rtree since
+// the type of the accumulator is a type parameter.
+func ReduceIntersect[A any](tree *Rtree, bb Rect, init A, fn func(acc A, obj Spatial) A) A {
+	acc := init
+	tree.ForEachInRect(bb, func(obj Spatial) bool {
+		acc = fn(acc, obj)
+		return true
+	})
+	return acc
+}
+
+// SearchResult is an object found by SearchIntersectBoxes along with the
+// bounds under which it is stored.
+type SearchResult struct {
+	Object Spatial
+	Bounds Rect
+}
+
+// SearchIntersectBoxes returns the objects that intersect the specified
+// rectangle like SearchIntersect, each with the bounding box stored for it
+// in the tree, which saves calling Bounds on the results when it is
+// expensive.  The bounding boxes are copies that can be modified freely.
+func (tree *Rtree) SearchIntersectBoxes(bb Rect) []SearchResult {
+	tree.checkDim(len(bb.p))
+	results := []SearchResult{}
+	tree.forEachInRect(tree.root, tree.tolerant(bb), func(e entry) bool {
+		results = append(results, SearchResult{e.obj, e.bb.clone()})
+		return true
+	})
+	return results
+}
+
+// forEachInRect calls fn for the leaf entries of the subtree n intersecting
+// bb and reports whether fn stopped the search.
+func (tree *Rtree) forEachInRect(n *node, bb Rect, fn func(e entry) bool) bool {
+	for _, e := range n.entries {
+		if !tree.overlaps(e.bounds(), bb, n.leaf) {
+			continue
+		}
+		if n.leaf {
+			if !fn(e) {
+				return true
+			}
+		} else if tree.forEachInRect(e.child, bb, fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// CountIntersect returns the number of objects that intersect the specified
+// rectangle, like len(SearchIntersect(bb)) but without collecting them.
+// Subtrees whose bounding box lies within bb aren't visited, since every node
+// keeps the number of objects below it.
+func (tree *Rtree) CountIntersect(bb Rect) int {
+	tree.checkDim(len(bb.p))
+	return tree.countIntersect(tree.root, tree.tolerant(bb))
+}
+
+func (tree *Rtree) countIntersect(n *node, bb Rect) int {
+	if n.leaf {
+		count := 0
+		for _, e := range n.entries {
+			if tree.overlaps(e.bb, bb, true) {
+				count++
+			}
+		}
+		return count
+	}
+
+	count := 0
+	for _, e := range n.entries {
+		switch ebb := e.bounds(); {
+		case tree.covers(bb, ebb):
+			count += e.child.count
+		case intersect(ebb, bb):
+			count += tree.countIntersect(e.child, bb)
+		}
+	}
+	return count
+}
+
+// EstimateIntersect returns the number of interior nodes whose bounding box
+// intersects bb that SearchIntersect(bb) would visit, starting from the
+// root.  It measures the cost of the search in node accesses without
+// examining the leaves or the objects in them, which helps choosing between a
+// search and a full scan.  It returns 0 if the tree is a single leaf.
+func (tree *Rtree) EstimateIntersect(bb Rect) int {
+	tree.checkDim(len(bb.p))
+	bb = tree.tolerant(bb)
+	if tree.root.leaf || !intersect(tree.root.computeBoundingBox(), bb) {
+		return 0
+	}
+	return tree.estimateIntersect(tree.root, bb)
+}
+
+func (tree *Rtree) estimateIntersect(n *node, bb Rect) int {
+	visited := 1
+	for _, e := range n.entries {
+		if !e.child.leaf && intersect(e.bounds(), bb) {
+			visited += tree.estimateIntersect(e.child, bb)
+		}
+	}
+	return visited
+}
+
+// covers tests whether every object within the bounding box ebb of an
+// interior entry intersects bb.  With HalfOpen, objects on the upper faces of
+// bb don't intersect it, so ebb must not reach them.
+func (tree *Rtree) covers(bb, ebb Rect) bool {
+	if !bb.ContainsRect(ebb) {
+		return false
+	}
+	if tree.HalfOpen {
+		for i := range bb.q {
+			if ebb.q[i] == bb.q[i] && bb.p[i] != bb.q[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// searchIntersect appends the objects of the subtree n intersecting bb to
+// results, and reports whether a filter aborted the search.
+func (tree *Rtree) searchIntersect(results []Spatial, n *node, bb Rect, filters []Filter) ([]Spatial, bool) {
 	for _, e := range n.entries {
-		if !intersect(e.bb, bb) {
+		if !tree.overlaps(e.bounds(), bb, n.leaf) {
 			continue
 		}
 
 		if !n.leaf {
-			results = tree.searchIntersect(results, e.child, bb, filters)
+			var abort bool
+			results, abort = tree.searchIntersect(results, e.child, bb, filters)
+			if abort {
+				return results, true
+			}
 			continue
 		}
 
@@ -667,19 +2573,378 @@ func (tree *Rtree) searchIntersect(results []Spatial, n *node, bb Rect, filters
 		}
 
 		if abort {
-			break
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// SearchWithinRadius returns all objects whose bounds come within radius of
+// p, i.e. whose bounding box is at a Euclidean distance of at most radius from
+// p.  The distance is measured to the bounding boxes rather than to their
+// centers, so an object containing p is always found.  Subtrees whose
+// bounding boxes are farther than radius from p are pruned.  It panics with a
+// DistError if radius is negative.
+func (tree *Rtree) SearchWithinRadius(p Point, radius float64, filters ...Filter) []Spatial {
+	tree.checkDim(len(p))
+	if radius < 0 {
+		panic(DistError(radius))
+	}
+	results, _ := tree.searchWithinRadius([]Spatial{}, tree.root, p, radius*radius, filters)
+	return results
+}
+
+// searchWithinRadius appends the objects of the subtree n whose squared
+// distance from p is at most r2 to results, and reports whether a filter
+// aborted the search.
+func (tree *Rtree) searchWithinRadius(results []Spatial, n *node, p Point, r2 float64, filters []Filter) ([]Spatial, bool) {
+	for _, e := range n.entries {
+		if e.bounds().MinDist(p) > r2 {
+			continue
+		}
+
+		if !n.leaf {
+			var abort bool
+			results, abort = tree.searchWithinRadius(results, e.child, p, r2, filters)
+			if abort {
+				return results, true
+			}
+			continue
+		}
+
+		refuse, abort := applyFilters(results, e.obj, filters)
+		if !refuse {
+			results = append(results, e.obj)
+		}
+
+		if abort {
+			return results, true
 		}
 	}
+	return results, false
+}
+
+// SearchContained returns all objects whose bounds are contained in the
+// specified rectangle.  Objects whose edges coincide with the boundary of bb
+// are contained.
+func (tree *Rtree) SearchContained(bb Rect, filters ...Filter) []Spatial {
+	tree.checkDim(len(bb.p))
+	results, _ := tree.searchContained([]Spatial{}, tree.root, tree.tolerant(bb), filters)
 	return results
 }
 
-// NearestNeighbor returns the closest object to the specified point.
+func (tree *Rtree) searchContained(results []Spatial, n *node, bb Rect, filters []Filter) ([]Spatial, bool) {
+	for _, e := range n.entries {
+		if !n.leaf {
+			// a subtree can hold contained objects as long as it meets bb
+			if !intersect(e.bounds(), bb) {
+				continue
+			}
+			var abort bool
+			results, abort = tree.searchContained(results, e.child, bb, filters)
+			if abort {
+				return results, true
+			}
+			continue
+		}
+
+		if !bb.ContainsRect(e.bb) {
+			continue
+		}
+
+		refuse, abort := applyFilters(results, e.obj, filters)
+		if !refuse {
+			results = append(results, e.obj)
+		}
+
+		if abort {
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// SearchContainingPoint returns all objects whose bounds contain p, including
+// objects having p on their boundary.
+func (tree *Rtree) SearchContainingPoint(p Point, filters ...Filter) []Spatial {
+	tree.checkDim(len(p))
+	if tree.Epsilon > 0 {
+		// the objects containing p within Epsilon are the ones intersecting
+		// the box of half-width Epsilon around p
+		results, _ := tree.searchIntersect([]Spatial{}, tree.root, p.ToRect(tree.Epsilon), filters)
+		return results
+	}
+	results, _ := tree.searchContainingPoint([]Spatial{}, tree.root, p, filters)
+	return results
+}
+
+// overlaps tests whether the bounds ebb of an entry intersect bb.  HalfOpen
+// only applies to the objects in leaves: the bounding boxes of interior
+// entries stay closed, since they must reach the objects on their upper faces.
+func (tree *Rtree) overlaps(ebb, bb Rect, leaf bool) bool {
+	if leaf && tree.HalfOpen {
+		return intersectHalfOpen(ebb, bb)
+	}
+	return intersect(ebb, bb)
+}
+
+// checkDim panics with a DimError if a query of dimension dim doesn't match
+// the dimension of tree, rather than failing later with an index out of range.
+func (tree *Rtree) checkDim(dim int) {
+	if dim != tree.Dim {
+		panic(DimError{tree.Dim, dim})
+	}
+}
+
+// tolerant returns the query bb enlarged by Epsilon.
+func (tree *Rtree) tolerant(bb Rect) Rect {
+	if tree.Epsilon > 0 {
+		return bb.Expand(tree.Epsilon)
+	}
+	return bb
+}
+
+func (tree *Rtree) searchContainingPoint(results []Spatial, n *node, p Point, filters []Filter) ([]Spatial, bool) {
+	for _, e := range n.entries {
+		if !tree.overlaps(e.bounds(), Rect{p, p}, n.leaf) {
+			continue
+		}
+
+		if !n.leaf {
+			var abort bool
+			results, abort = tree.searchContainingPoint(results, e.child, p, filters)
+			if abort {
+				return results, true
+			}
+			continue
+		}
+
+		refuse, abort := applyFilters(results, e.obj, filters)
+		if !refuse {
+			results = append(results, e.obj)
+		}
+
+		if abort {
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// SearchQuadrant returns all objects lying in the quadrant (or, in higher
+// dimensions, orthant) of space specified relative to origin.  For every
+// dimension i, a positive signs[i] selects objects whose bounds lie entirely
+// at or above origin[i], a negative signs[i] selects objects whose bounds lie
+// entirely at or below origin[i], and zero leaves the dimension unconstrained.
+// Both origin and signs must have tree.Dim elements.
+func (tree *Rtree) SearchQuadrant(origin Point, signs []int, filters ...Filter) []Spatial {
+	tree.checkDim(len(origin))
+	tree.checkDim(len(signs))
+	results, _ := tree.searchQuadrant([]Spatial{}, tree.root, origin, signs, filters)
+	return results
+}
+
+func (tree *Rtree) searchQuadrant(results []Spatial, n *node, origin Point, signs []int, filters []Filter) ([]Spatial, bool) {
+	for _, e := range n.entries {
+		if !inQuadrant(e.bounds(), origin, signs, !n.leaf) {
+			continue
+		}
+
+		if !n.leaf {
+			var abort bool
+			results, abort = tree.searchQuadrant(results, e.child, origin, signs, filters)
+			if abort {
+				return results, true
+			}
+			continue
+		}
+
+		refuse, abort := applyFilters(results, e.obj, filters)
+		if !refuse {
+			results = append(results, e.obj)
+		}
+
+		if abort {
+			return results, true
+		}
+	}
+	return results, false
+}
+
+// inQuadrant tests whether bb lies in the quadrant given by origin and signs.
+// If partial is true, it only tests whether bb reaches into the quadrant,
+// which is the condition for a subtree to contain matching objects.
+func inQuadrant(bb Rect, origin Point, signs []int, partial bool) bool {
+	for i, sign := range signs {
+		lo, hi := bb.p[i], bb.q[i]
+		if partial {
+			lo, hi = hi, lo
+		}
+		if sign > 0 && lo < origin[i] || sign < 0 && hi > origin[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NearestNeighbor returns the closest object to the specified point, or nil
+// if the tree is empty.  Distances are measured from p to the bounding boxes
+// of the objects.  Among objects at the same distance, the first one found is
+// returned, which is deterministic for a given tree.
 // Implemented per "Nearest Neighbor Queries" by Roussopoulos et al
 func (tree *Rtree) NearestNeighbor(p Point) Spatial {
-	obj, _ := tree.nearestNeighbor(p, tree.root, math.MaxFloat64, nil)
+	tree.checkDim(len(p))
+	obj, _ := tree.nearestNeighborVisits(p, new(int))
 	return obj
 }
 
+// nearestNeighborVisits is NearestNeighbor, also counting the nodes visited
+// in *visited.
+func (tree *Rtree) nearestNeighborVisits(p Point, visited *int) (Spatial, float64) {
+	branches, branchDists := tree.branchBuffers()
+	return tree.nearestNeighbor(p, tree.root, math.MaxFloat64, math.MaxFloat64, nil, branches, branchDists, visited)
+}
+
+// NearestNeighborBatch returns the closest object to each of ps, as
+// NearestNeighbor does, in the same order as ps.  The objects are nil if the
+// tree is empty.  The points are searched in the order of a Z-order curve, so
+// that consecutive searches mostly visit the same nodes while they are in the
+// CPU caches, and the search buffers are allocated once for the whole batch.
+func (tree *Rtree) NearestNeighborBatch(ps []Point) []Spatial {
+	for _, p := range ps {
+		tree.checkDim(len(p))
+	}
+	objs := make([]Spatial, len(ps))
+	if len(tree.root.entries) == 0 {
+		return objs
+	}
+
+	bb := tree.root.computeBoundingBox()
+	keys := make([]uint64, len(ps))
+	order := make([]int, len(ps))
+	for i, p := range ps {
+		keys[i] = zOrder(p, bb)
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return keys[order[i]] < keys[order[j]] })
+
+	branches, branchDists := tree.branchBuffers()
+	var visited int
+	for _, i := range order {
+		objs[i], _ = tree.nearestNeighbor(ps[i], tree.root, math.MaxFloat64, math.MaxFloat64, nil, branches, branchDists, &visited)
+	}
+	return objs
+}
+
+// zOrder returns the position of p along the Z-order curve over bb, which
+// interleaves the bits of the coordinates of p quantized within bb.  Points
+// outside of bb are clamped to it.
+func zOrder(p Point, bb Rect) uint64 {
+	bits := min(64/len(p), 32)
+	cells := float64(uint64(1) << bits)
+	var key uint64
+	for i := range p {
+		t := 0.0
+		if l := bb.q[i] - bb.p[i]; l > 0 {
+			t = (p[i] - bb.p[i]) / l
+		}
+		c := uint64(math.Max(0, math.Min(t*cells, cells-1)))
+		for b := 0; b < bits; b++ {
+			key |= (c >> b & 1) << (b*len(p) + i)
+		}
+	}
+	return key
+}
+
+// branchBuffers preallocates the buffers for sorting the branches of nearest
+// neighbor searches.  At each level of the tree, the buffers slide by the
+// number of entries in the node.  Leaves are scanned linearly and don't need
+// any buffers.
+func (tree *Rtree) branchBuffers() (branches []entry, branchDists []float64) {
+	if !tree.root.leaf {
+		maxBufSize := tree.MaxChildren * tree.Depth()
+		branches = make([]entry, maxBufSize)
+		branchDists = make([]float64, maxBufSize)
+	}
+	return
+}
+
+// GetAll returns all objects stored in the tree, in the order of a
+// depth-first traversal of the tree.  The order is the same for every call as
+// long as the tree isn't modified.
+func (tree *Rtree) GetAll() []Spatial {
+	return tree.root.getAll(make([]Spatial, 0, tree.size))
+}
+
+// getAll appends the objects of all leaves below n to objs.
+func (n *node) getAll(objs []Spatial) []Spatial {
+	for _, e := range n.entries {
+		if n.leaf {
+			objs = append(objs, e.obj)
+		} else {
+			objs = e.child.getAll(objs)
+		}
+	}
+	return objs
+}
+
+// Walk performs a pre-order traversal of the tree, calling visit for the
+// entry of every node below the root and for every stored object.  For
+// interior entries, bb is the bounding box of the node, obj is nil and leaf is
+// false, and returning false prunes the descent into the node.  For stored
+// objects, bb is the bounding box of obj and leaf is true.  bb is a copy
+// that visit can modify freely, but visit must not modify the tree.
+func (tree *Rtree) Walk(visit func(bb Rect, obj Spatial, leaf bool) bool) {
+	tree.root.walk(visit)
+}
+
+func (n *node) walk(visit func(bb Rect, obj Spatial, leaf bool) bool) {
+	for _, e := range n.entries {
+		if visit(e.bounds().clone(), e.obj, n.leaf) && !n.leaf {
+			e.child.walk(visit)
+		}
+	}
+}
+
+// EqualContents reports whether tree and other store the same objects, each
+// the same number of times, regardless of the structure of the trees.  Objects
+// are compared with eq, or with == like Delete does if eq is nil, but only to
+// the objects of the other tree stored under the same bounds, so eq must not
+// consider objects with different bounds equal.
+func (tree *Rtree) EqualContents(other *Rtree, eq Comparator) bool {
+	if tree.size != other.size {
+		return false
+	}
+	if tree.size == 0 {
+		return true
+	}
+	if tree.Dim != other.Dim {
+		return false
+	}
+	if eq == nil {
+		eq = defaultComparator
+	}
+
+	// match every object of tree with an unmatched object of other among
+	// the ones sorted under the same bounds
+	others := other.root.leafEntries(make([]entry, 0, other.size))
+	slices.SortFunc(others, func(a, b entry) int { return compareRects(a.bb, b.bb) })
+	matched := make([]bool, len(others))
+	for _, e := range tree.root.leafEntries(make([]entry, 0, tree.size)) {
+		i, _ := slices.BinarySearchFunc(others, e.bb, func(o entry, bb Rect) int { return compareRects(o.bb, bb) })
+		for ; i < len(others) && compareRects(others[i].bb, e.bb) == 0; i++ {
+			if !matched[i] && eq(e.obj, others[i].obj) {
+				break
+			}
+		}
+		if i == len(others) || compareRects(others[i].bb, e.bb) != 0 {
+			return false
+		}
+		matched[i] = true
+	}
+	return true
+}
+
 // GetAllBoundingBoxes returning slice of bounding boxes by traversing tree. Slice
 // includes bounding boxes from all non-leaf nodes.
 func (tree *Rtree) GetAllBoundingBoxes() []Rect {
@@ -690,6 +2955,33 @@ func (tree *Rtree) GetAllBoundingBoxes() []Rect {
 	return rects
 }
 
+// Bounds returns the smallest rectangle containing the bounds of every object
+// stored in the tree, computed from the entries of the root, and whether the
+// tree holds any objects; if it is empty, it returns the zero Rect and false.
+// The result is a copy that can be modified freely.
+func (tree *Rtree) Bounds() (Rect, bool) {
+	if len(tree.root.entries) == 0 {
+		return Rect{}, false
+	}
+	dim := len(tree.root.entries[0].bounds().p)
+	bb := Rect{make(Point, dim), make(Point, dim)}
+	tree.root.boundingBoxInto(bb)
+	return bb, true
+}
+
+// RootBounds returns copies of the bounding boxes of the entries of the root,
+// the top level of the tree, for custom traversals and visualizations.  When
+// the tree is a single leaf, these are the bounds of its objects.  Their
+// union is the bounding box of the whole tree, and the result is empty if
+// the tree is empty.
+func (tree *Rtree) RootBounds() []Rect {
+	rects := make([]Rect, len(tree.root.entries))
+	for i, e := range tree.root.entries {
+		rects[i] = e.bounds().clone()
+	}
+	return rects
+}
+
 // utilities for sorting slices of entries
 
 type entrySlice struct {
@@ -711,17 +3003,19 @@ func (s entrySlice) Less(i, j int) bool {
 func sortEntries(p Point, entries []entry) ([]entry, []float64) {
 	sorted := make([]entry, len(entries))
 	dists := make([]float64, len(entries))
-	return sortPreallocEntries(p, entries, sorted, dists)
+	return sortPreallocEntries(p.minDist, entries, sorted, dists)
 }
 
-func sortPreallocEntries(p Point, entries, sorted []entry, dists []float64) ([]entry, []float64) {
+// sortPreallocEntries sorts entries by the distance dist assigns to their
+// bounding boxes, using the preallocated slices sorted and dists.
+func sortPreallocEntries(dist func(Rect) float64, entries, sorted []entry, dists []float64) ([]entry, []float64) {
 	// use preallocated slices
 	sorted = sorted[:len(entries)]
 	dists = dists[:len(entries)]
 
 	for i := 0; i < len(entries); i++ {
 		sorted[i] = entries[i]
-		dists[i] = p.minDist(entries[i].bb)
+		dists[i] = dist(entries[i].bounds())
 	}
 	sort.Sort(entrySlice{sorted, dists})
 	return sorted, dists
@@ -730,7 +3024,7 @@ func sortPreallocEntries(p Point, entries, sorted []entry, dists []float64) ([]e
 func pruneEntries(p Point, entries []entry, minDists []float64) []entry {
 	minMinMaxDist := math.MaxFloat64
 	for i := range entries {
-		minMaxDist := p.minMaxDist(entries[i].bb)
+		minMaxDist := p.minMaxDist(entries[i].bounds())
 		if minMaxDist < minMinMaxDist {
 			minMinMaxDist = minMaxDist
 		}
@@ -755,62 +3049,335 @@ func pruneEntriesMinDist(d float64, entries []entry, minDists []float64) []entry
 	return entries[:i]
 }
 
-func (tree *Rtree) nearestNeighbor(p Point, n *node, d float64, nearest Spatial) (Spatial, float64) {
+// nearestNeighbor finds the object in the subtree n closer to p than the
+// squared distance d, returning it and its squared distance, or nearest and d
+// if there is none.  bound is the smallest MINMAXDIST of the branches visited
+// on the way to n: some object lies within bound of p, so branches farther
+// than bound can be skipped even before such an object is found.
+func (tree *Rtree) nearestNeighbor(p Point, n *node, d, bound float64, nearest Spatial, b []entry, bd []float64, visited *int) (Spatial, float64) {
+	*visited++
 	if n.leaf {
 		for _, e := range n.entries {
-			dist := math.Sqrt(p.minDist(e.bb))
+			dist := e.bb.MinDist(p)
 			if dist < d {
 				d = dist
 				nearest = e.obj
 			}
 		}
-	} else {
-		// Search only through entries with minDist <= minMinMaxDist,
-		// where minDist is the distance between a point and a rectangle,
-		// and minMaxDist is the smallest value among the maximum distance across all axes.
-		//
-		// Entries with minDist > minMinMaxDist are guaranteed to be farther away than some other entry.
-		//
-		// For more details, please consult
-		// N. Roussopoulos, S. Kelley and F. Vincent, ACM SIGMOD, pages 71-79, 1995.
-		minMinMaxDist := math.MaxFloat64
+		return nearest, d
+	}
+
+	// Search only through entries with minDist <= minMinMaxDist,
+	// where minDist is the distance between a point and a rectangle,
+	// and minMaxDist is the smallest value among the maximum distance across all axes.
+	//
+	// Entries with minDist > minMinMaxDist are guaranteed to be farther away than some other entry.
+	// The bound also holds in the subtrees, where it prunes branches of the
+	// descendants (downward pruning) until an object closer than it is found
+	// (upward pruning).
+	//
+	// For more details, please consult
+	// N. Roussopoulos, S. Kelley and F. Vincent, ACM SIGMOD, pages 71-79, 1995.
+	for _, e := range n.entries {
+		minMaxDist := p.minMaxDist(e.bounds())
+		if minMaxDist < bound {
+			bound = minMaxDist
+		}
+	}
+
+	// Visit the branches closest to p first, and stop as soon as the
+	// remaining ones can't contain anything closer than the best object
+	// found so far.
+	branches, branchDists := sortPreallocEntries(p.minDist, n.entries, b, bd)
+	for i, e := range branches {
+		if branchDists[i] > bound || branchDists[i] > d {
+			break
+		}
+		nearest, d = tree.nearestNeighbor(p, e.child, d, bound, nearest, b[len(n.entries):], bd[len(n.entries):], visited)
+	}
+	return nearest, d
+}
+
+// ApproxNearest returns an object whose distance from p is at most 1+epsilon
+// times the distance of the closest object, or nil if the tree is empty.
+// Distances are measured from p to the bounding boxes of the objects, like
+// NearestNeighbor.  Subtrees are pruned as soon as their bounding box is
+// farther than the best distance found so far divided by 1+epsilon, so larger
+// values of epsilon visit fewer nodes, and an epsilon of 0 finds the closest
+// object exactly.  It panics with a DistError if epsilon is negative.
+func (tree *Rtree) ApproxNearest(p Point, epsilon float64) Spatial {
+	tree.checkDim(len(p))
+	if epsilon < 0 || math.IsNaN(epsilon) {
+		panic(DistError(epsilon))
+	}
+	branches, branchDists := tree.branchBuffers()
+	// distances are squared, and so is the pruning factor
+	factor := (1 + epsilon) * (1 + epsilon)
+	obj, _ := tree.approxNearest(p, factor, tree.root, math.MaxFloat64, nil, branches, branchDists)
+	return obj
+}
+
+// approxNearest finds an object in the subtree n closer to p than the squared
+// distance d, returning it and its squared distance, or nearest and d if
+// there is none.  Branches are skipped when their squared distance times
+// factor exceeds d.
+func (tree *Rtree) approxNearest(p Point, factor float64, n *node, d float64, nearest Spatial, b []entry, bd []float64) (Spatial, float64) {
+	if n.leaf {
 		for _, e := range n.entries {
-			minMaxDist := p.minMaxDist(e.bb)
-			if minMaxDist < minMinMaxDist {
-				minMinMaxDist = minMaxDist
+			if dist := e.bb.MinDist(p); dist < d {
+				d = dist
+				nearest = e.obj
 			}
 		}
+		return nearest, d
+	}
+
+	branches, branchDists := sortPreallocEntries(p.minDist, n.entries, b, bd)
+	for i, e := range branches {
+		if branchDists[i]*factor > d {
+			break
+		}
+		nearest, d = tree.approxNearest(p, factor, e.child, d, nearest, b[len(n.entries):], bd[len(n.entries):])
+	}
+	return nearest, d
+}
 
+// NearestInRect returns the closest object to p among the objects that
+// intersect bb, as SearchIntersect finds them, or nil if there is none.
+// Distances are measured from p to the bounding boxes of the objects, like
+// NearestNeighbor, and p doesn't need to lie in bb.  Subtrees not
+// intersecting bb are skipped, so that objects outside bb are never
+// considered, such as when looking for the nearest point of interest visible
+// in a viewport.
+func (tree *Rtree) NearestInRect(p Point, bb Rect) Spatial {
+	tree.checkDim(len(p))
+	tree.checkDim(len(bb.p))
+	branches, branchDists := tree.branchBuffers()
+	obj, _ := tree.nearestInRect(p, tree.tolerant(bb), tree.root, math.MaxFloat64, nil, branches, branchDists)
+	return obj
+}
+
+// nearestInRect finds the object in the subtree n intersecting bb and closer
+// to p than the squared distance d, returning it and its squared distance, or
+// nearest and d if there is none.  Unlike nearestNeighbor, it can't prune
+// branches by MINMAXDIST, since the object guaranteeing it may lie outside bb.
+func (tree *Rtree) nearestInRect(p Point, bb Rect, n *node, d float64, nearest Spatial, b []entry, bd []float64) (Spatial, float64) {
+	if n.leaf {
 		for _, e := range n.entries {
-			minDist := p.minDist(e.bb)
-			if minDist > minMinMaxDist {
+			if !tree.overlaps(e.bb, bb, true) {
 				continue
 			}
-
-			subNearest, dist := tree.nearestNeighbor(p, e.child, d, nearest)
-			if dist < d {
+			if dist := e.bb.MinDist(p); dist < d {
 				d = dist
-				nearest = subNearest
+				nearest = e.obj
 			}
 		}
+		return nearest, d
 	}
 
+	branches, branchDists := sortPreallocEntries(p.minDist, n.entries, b, bd)
+	for i, e := range branches {
+		if branchDists[i] > d {
+			break
+		}
+		if !tree.overlaps(e.bounds(), bb, false) {
+			continue
+		}
+		nearest, d = tree.nearestInRect(p, bb, e.child, d, nearest, b[len(n.entries):], bd[len(n.entries):])
+	}
 	return nearest, d
 }
 
-// NearestNeighbors gets the closest Spatials to the Point.
+// ClosestPair returns the two distinct stored objects closest to each other
+// and their distance, or nil, nil and 0 if the tree holds fewer than two
+// objects.  The distance between objects is the Euclidean distance between
+// their bounding boxes, as computed by Rect.DistTo, so overlapping objects
+// are at distance zero.  Among pairs at the same distance, the first one
+// found is returned.
+//
+// The tree is joined with itself: pairs of subtrees are visited closest
+// first, and skipped once their bounding boxes are no closer than the best
+// pair found so far, so that far-apart subtrees are never compared.
+func (tree *Rtree) ClosestPair() (Spatial, Spatial, float64) {
+	if tree.size < 2 {
+		return nil, nil, 0
+	}
+	cp := closestPair{dist: math.Inf(1)}
+	cp.search(tree.root, tree.root)
+	return cp.a, cp.b, math.Sqrt(cp.dist)
+}
+
+// closestPair holds the best pair of objects found by ClosestPair and their
+// squared distance.
+type closestPair struct {
+	a, b Spatial
+	dist float64
+}
+
+// nodePair is a pair of subtrees visited by ClosestPair and the squared
+// distance between their bounding boxes.
+type nodePair struct {
+	n, m *node
+	dist float64
+}
+
+// search looks for a closer pair made of an object of n and an object of m,
+// two nodes at the same level, which may be the same node.
+func (cp *closestPair) search(n, m *node) {
+	if n.leaf {
+		for i, e := range n.entries {
+			others := m.entries
+			if n == m {
+				others = others[i+1:]
+			}
+			for _, f := range others {
+				if d := e.bb.minDistRect(f.bb); d < cp.dist {
+					cp.a, cp.b, cp.dist = e.obj, f.obj, d
+				}
+			}
+		}
+		return
+	}
+
+	var pairs []nodePair
+	for i, e := range n.entries {
+		others := m.entries
+		if n == m {
+			// a child is paired with itself as well
+			others = others[i:]
+		}
+		for _, f := range others {
+			if d := e.bounds().minDistRect(f.bounds()); d < cp.dist {
+				pairs = append(pairs, nodePair{e.child, f.child, d})
+			}
+		}
+	}
+	slices.SortStableFunc(pairs, func(a, b nodePair) int {
+		return cmp.Compare(a.dist, b.dist)
+	})
+	for _, p := range pairs {
+		if p.dist >= cp.dist {
+			break
+		}
+		cp.search(p.n, p.m)
+	}
+}
+
+// NearestNeighbors gets the k closest Spatials to the Point, sorted by
+// increasing distance from p to their bounding boxes.  If the tree holds fewer
+// than k objects, all of them are returned.
+//
+// Branches are visited in order of MINDIST and pruned once their MINDIST
+// exceeds the distance to the k-th best candidate found so far, per "Nearest
+// Neighbor Queries" by Roussopoulos et al.  Their MINMAXDIST rule only bounds
+// the distance to the single nearest object and is used by NearestNeighbor.
 func (tree *Rtree) NearestNeighbors(k int, p Point, filters ...Filter) []Spatial {
-	// preallocate the buffers for sortings the branches. At each level of the
-	// tree, we slide the buffer by the number of entries in the node.
-	maxBufSize := tree.MaxChildren * tree.Depth()
-	branches := make([]entry, maxBufSize)
-	branchDists := make([]float64, maxBufSize)
+	tree.checkDim(len(p))
+	return tree.kNearest(k, p.minDist, filters)
+}
+
+// NearestToRect gets the k objects closest to the query rectangle, sorted by
+// increasing distance. The distance between an object and the query is the
+// distance between their bounding boxes as computed by Rect.DistTo, so every
+// object intersecting the query is at distance zero. If the tree holds fewer
+// than k objects, all of them are returned.
+func (tree *Rtree) NearestToRect(k int, query Rect, filters ...Filter) []Spatial {
+	tree.checkDim(len(query.p))
+	return tree.kNearest(k, query.minDistRect, filters)
+}
+
+// KNNBounds gets the k objects closest to p like NearestNeighbors, and also
+// returns the smallest rectangle containing all of them, which can serve as a
+// query window adapted to the density of objects around p.  The rectangle is
+// the zero Rect if the tree is empty.
+func (tree *Rtree) KNNBounds(k int, p Point, filters ...Filter) (Rect, []Spatial) {
+	objs := tree.NearestNeighbors(k, p, filters...)
+	return boundingBoxOf(objs), objs
+}
+
+// NearestNeighborsFilter gets the k closest Spatials to p for which filter
+// returns true, sorted by increasing distance like NearestNeighbors.  Only the
+// objects passing filter count toward the k results, and branches are still
+// pruned by their distance to p, but filter is only called on stored objects,
+// so when few objects pass it, many nodes may have to be visited.
+func (tree *Rtree) NearestNeighborsFilter(k int, p Point, filter func(obj Spatial) bool) []Spatial {
+	return tree.NearestNeighbors(k, p, func(results []Spatial, object Spatial) (refuse, abort bool) {
+		return !filter(object), false
+	})
+}
+
+// NearestIter returns a function yielding the objects of the tree one at a
+// time in order of increasing distance from p to their bounding boxes, and
+// false once all of them have been returned.  Only the nodes needed to find
+// the next object are visited, so the caller can stop at any point without
+// the cost of a k-nearest search for a large k.  The tree must not be
+// modified while the function is in use.
+//
+// Implemented per "Distance Browsing in Spatial Databases" by G. Hjaltason
+// and H. Samet, ACM TODS 24(2), pages 265-318, 1999: a single priority queue
+// holds both nodes and objects keyed by their distance to p, and an object is
+// returned when it reaches the front of the queue.
+func (tree *Rtree) NearestIter(p Point) func() (Spatial, bool) {
+	tree.checkDim(len(p))
+	q := &browseQueue{}
+	for _, e := range tree.root.entries {
+		heap.Push(q, browseItem{e, p.minDist(e.bounds())})
+	}
+	return func() (Spatial, bool) {
+		for q.Len() > 0 {
+			item := heap.Pop(q).(browseItem)
+			if item.e.child == nil {
+				return item.e.obj, true
+			}
+			for _, e := range item.e.child.entries {
+				heap.Push(q, browseItem{e, p.minDist(e.bounds())})
+			}
+		}
+		return nil, false
+	}
+}
+
+// browseItem is a node or an object queued by NearestIter.
+type browseItem struct {
+	e    entry
+	dist float64
+}
+
+// browseQueue is a min-heap of browseItems by distance, which returns objects
+// before nodes at the same distance.
+type browseQueue []browseItem
+
+func (q browseQueue) Len() int { return len(q) }
+
+func (q browseQueue) Less(i, j int) bool {
+	if q[i].dist != q[j].dist {
+		return q[i].dist < q[j].dist
+	}
+	return q[i].e.child == nil && q[j].e.child != nil
+}
+
+func (q browseQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *browseQueue) Push(x any) { *q = append(*q, x.(browseItem)) }
+
+func (q *browseQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// kNearest finds the k objects whose bounding boxes are closest according to
+// dist, which must return a lower bound of the distance to anything contained
+// in the given rectangle.
+func (tree *Rtree) kNearest(k int, dist func(Rect) float64, filters []Filter) []Spatial {
+	branches, branchDists := tree.branchBuffers()
 
 	// allocate the buffers for the results
 	dists := make([]float64, 0, k)
 	objs := make([]Spatial, 0, k)
 
-	objs, _, _ = tree.nearestNeighbors(k, p, tree.root, dists, objs, filters, branches, branchDists)
+	objs, _, _ = tree.nearestNeighbors(k, dist, tree.root, dists, objs, filters, branches, branchDists)
 	return objs
 }
 
@@ -847,24 +3414,23 @@ func insertNearest(k int, dists []float64, nearest []Spatial, dist float64, obj
 	return dists, nearest, false
 }
 
-func (tree *Rtree) nearestNeighbors(k int, p Point, n *node, dists []float64, nearest []Spatial, filters []Filter, b []entry, bd []float64) ([]Spatial, []float64, bool) {
+func (tree *Rtree) nearestNeighbors(k int, dist func(Rect) float64, n *node, dists []float64, nearest []Spatial, filters []Filter, b []entry, bd []float64) ([]Spatial, []float64, bool) {
 	var abort bool
 	if n.leaf {
 		for _, e := range n.entries {
-			dist := p.minDist(e.bb)
-			dists, nearest, abort = insertNearest(k, dists, nearest, dist, e.obj, filters)
+			dists, nearest, abort = insertNearest(k, dists, nearest, dist(e.bb), e.obj, filters)
 			if abort {
 				break
 			}
 		}
 	} else {
-		branches, branchDists := sortPreallocEntries(p, n.entries, b, bd)
+		branches, branchDists := sortPreallocEntries(dist, n.entries, b, bd)
 		// only prune if buffer has k elements
 		if l := len(dists); l >= k {
 			branches = pruneEntriesMinDist(dists[l-1], branches, branchDists)
 		}
 		for _, e := range branches {
-			nearest, dists, abort = tree.nearestNeighbors(k, p, e.child, dists, nearest, filters, b[len(n.entries):], bd[len(n.entries):])
+			nearest, dists, abort = tree.nearestNeighbors(k, dist, e.child, dists, nearest, filters, b[len(n.entries):], bd[len(n.entries):])
 			if abort {
 				break
 			}
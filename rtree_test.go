@@ -1,9 +1,13 @@
 package rtreego
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -217,13 +221,13 @@ func TestChooseLeafNode(t *testing.T) {
 		rt := Rtree{}
 		rt.root = &node{}
 
-		leaf0 := &node{rt.root, true, []entry{}, 1}
+		leaf0 := newNode(rt.root, true, []entry{}, 1)
 		entry0 := entry{test.bb0, leaf0, nil}
 
-		leaf1 := &node{rt.root, true, []entry{}, 1}
+		leaf1 := newNode(rt.root, true, []entry{}, 1)
 		entry1 := entry{test.bb1, leaf1, nil}
 
-		leaf2 := &node{rt.root, true, []entry{}, 1}
+		leaf2 := newNode(rt.root, true, []entry{}, 1)
 		entry2 := entry{test.bb2, leaf2, nil}
 
 		rt.root.entries = []entry{entry0, entry1, entry2}
@@ -267,6 +271,84 @@ func TestPickNext(t *testing.T) {
 	}
 }
 
+func TestPickNextTieBreak(t *testing.T) {
+	left := &node{entries: []entry{{bb: mustRect(Point{0}, []float64{1})}}}
+	right := &node{entries: []entry{{bb: mustRect(Point{10}, []float64{1})}}}
+
+	// both entries prefer left by 6, but the point enlarges it less
+	segment := entry{bb: mustRect(Point{2}, []float64{1})}
+	point := entry{bb: mustRect(Point{2.5}, []float64{0})}
+	if chosen := pickNext(left, right, []entry{segment, point}); chosen != 1 {
+		t.Errorf("pickNext chose entry %d, expected the point enlarging its group the least", chosen)
+	}
+
+	// complete ties are broken by index
+	if chosen := pickNext(left, right, []entry{point, point}); chosen != 0 {
+		t.Errorf("pickNext chose entry %d of identical entries, expected the first", chosen)
+	}
+}
+
+// pickSplitter is the quadratic split choosing the next entry with pick.
+type pickSplitter func(left, right *node, entries []entry) int
+
+func (pick pickSplitter) Split(bounds []Rect, minGroupSize int) []bool {
+	n := &node{leaf: true, level: 1, entries: make([]entry, len(bounds))}
+	for i, bb := range bounds {
+		n.entries[i] = entry{bb: bb, obj: splitIndex(i)}
+	}
+	l, r := n.pickSeeds()
+	left, _ := n.splitFromSeeds(minGroupSize, l, r, pick)
+	first := make([]bool, len(bounds))
+	for _, e := range left.entries {
+		first[e.obj.(splitIndex)] = true
+	}
+	return first
+}
+
+// pickNextFirstTie is pickNext breaking every tie of preference in favor of
+// the first entry, without comparing enlargements.
+func pickNextFirstTie(left, right *node, entries []entry) (next int) {
+	maxDiff, maxMarginDiff := -1.0, -1.0
+	leftBB, rightBB := left.computeBoundingBox(), right.computeBoundingBox()
+	for i, e := range entries {
+		l, r := leftBB.Union(e.bb), rightBB.Union(e.bb)
+		d := math.Abs((l.Size() - leftBB.Size()) - (r.Size() - rightBB.Size()))
+		m := math.Abs((l.Margin() - leftBB.Margin()) - (r.Margin() - rightBB.Margin()))
+		if d > maxDiff || d == maxDiff && m > maxMarginDiff {
+			maxDiff, maxMarginDiff = d, m
+			next = i
+		}
+	}
+	return next
+}
+
+func TestPickNextGridOverlap(t *testing.T) {
+	var overlap, firstTieOverlap float64
+	for seed := int64(1); seed <= 3; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		var things []Spatial
+		for _, cell := range rnd.Perm(1600) {
+			r := mustRect(Point{float64(cell % 40), float64(cell / 40)}, []float64{0.5, 0.5})
+			things = append(things, &r)
+		}
+		for _, max := range []int{6, 10, 16} {
+			rt, firstTie := NewTree(2, 2, max), NewTree(2, 2, max)
+			rt.Splitter = pickSplitter(pickNext)
+			firstTie.Splitter = pickSplitter(pickNextFirstTie)
+			for _, thing := range things {
+				rt.Insert(thing)
+				firstTie.Insert(thing)
+			}
+			verify(t, rt)
+			overlap += rt.Stats().Overlap
+			firstTieOverlap += firstTie.Stats().Overlap
+		}
+	}
+	if overlap >= firstTieOverlap {
+		t.Errorf("siblings overlap by %v on grids, no less than %v when ties go to the first entry", overlap, firstTieOverlap)
+	}
+}
+
 func TestSplit(t *testing.T) {
 	entry1 := entry{bb: mustRect(Point{-3, -1}, []float64{2, 1})}
 	entry2 := entry{bb: mustRect(Point{1, 2}, []float64{1, 1})}
@@ -306,6 +388,314 @@ func TestSplitUnderflow(t *testing.T) {
 	}
 }
 
+func TestSplitKeepsEntries(t *testing.T) {
+	const maxChildren = 8
+	things := randomRects(rand.New(rand.NewSource(1)), maxChildren+1)
+	entries := make([]entry, len(things))
+	for i, thing := range things {
+		entries[i] = entry{bb: thing.Bounds(), obj: thing}
+	}
+
+	splits := map[string]func(n *node) (*node, *node){
+		"quadratic": func(n *node) (*node, *node) { return n.split(3) },
+		"linear":    func(n *node) (*node, *node) { return n.splitLinear(3) },
+		"seeds in reverse order": func(n *node) (*node, *node) {
+			return n.splitFromSeeds(3, maxChildren, 0, pickNext)
+		},
+	}
+	for name, split := range splits {
+		n := &node{leaf: true, level: 1, entries: append([]entry(nil), entries...)}
+		l, r := split(n)
+
+		seen := make(map[Spatial]int)
+		for _, e := range append(append([]entry(nil), l.entries...), r.entries...) {
+			seen[e.obj]++
+		}
+		for _, thing := range things {
+			if seen[thing] != 1 {
+				t.Errorf("%s: %v found %d times after the split", name, thing, seen[thing])
+			}
+		}
+		if len(l.entries)+len(r.entries) != len(things) {
+			t.Errorf("%s: split into %d and %d entries, expected %d in total", name, len(l.entries), len(r.entries), len(things))
+		}
+	}
+}
+
+func TestLinearPickSeeds(t *testing.T) {
+	entries := []entry{
+		{bb: mustRect(Point{1, 1}, []float64{1, 1})},
+		{bb: mustRect(Point{-5, 0}, []float64{1, 1})}, // lowest high side along x
+		{bb: mustRect(Point{0, 2}, []float64{1, 1})},
+		{bb: mustRect(Point{9, 0}, []float64{1, 1})}, // highest low side along x
+		{bb: mustRect(Point{2, 0}, []float64{1, 3})},
+	}
+	n := &node{entries: entries}
+
+	if l, r := n.linearPickSeeds(); l != 1 || r != 3 {
+		t.Errorf("linearPickSeeds() = %d, %d, expected 1, 3", l, r)
+	}
+}
+
+func TestLinearPickSeedsIdentical(t *testing.T) {
+	bb := mustRect(Point{1, 1}, []float64{1, 1})
+	n := &node{entries: []entry{{bb: bb}, {bb: bb}, {bb: bb}}}
+
+	if l, r := n.linearPickSeeds(); l == r || l > r {
+		t.Errorf("linearPickSeeds() = %d, %d, expected two ordered distinct entries", l, r)
+	}
+}
+
+func TestLinearSplitStrategy(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+
+	for _, tc := range tests(2, 4, 10, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			rt.SplitStrategy = LinearSplit
+			extra := randomRects(rnd, 1000)
+			for _, thing := range extra {
+				rt.Insert(thing)
+			}
+			verify(t, rt)
+			if level := underfull(rt.root, rt.MinChildren); level != 0 {
+				t.Errorf("underfull node at level %d", level)
+			}
+
+			all := mustRect(Point{-1, -1}, []float64{200, 200})
+			q := rt.SearchIntersect(all)
+			ensureDisorderedSubset(t, q, append(things, extra...))
+			if len(q) != len(things)+len(extra) {
+				t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(things)+len(extra))
+			}
+		})
+	}
+}
+
+func TestSplitRStar(t *testing.T) {
+	// two clusters along the second axis, stretched along the first one
+	entries := []entry{
+		{bb: mustRect(Point{0, 0}, []float64{10, 1})},
+		{bb: mustRect(Point{1, 10}, []float64{10, 1})},
+		{bb: mustRect(Point{2, 0.5}, []float64{10, 1})},
+		{bb: mustRect(Point{3, 10.5}, []float64{10, 1})},
+		{bb: mustRect(Point{4, 1}, []float64{10, 1})},
+		{bb: mustRect(Point{5, 11}, []float64{10, 1})},
+	}
+	n := &node{entries: entries}
+
+	l, r := n.splitRStar(2)
+	expLeft := mustRect(Point{0, 0}, []float64{14, 2})
+	expRight := mustRect(Point{1, 10}, []float64{14, 2})
+
+	lbb := l.computeBoundingBox()
+	rbb := r.computeBoundingBox()
+	if !lbb.Equal(expLeft) {
+		t.Errorf("expected left.bb = %s, got %s", expLeft, lbb)
+	}
+	if !rbb.Equal(expRight) {
+		t.Errorf("expected right.bb = %s, got %s", expRight, rbb)
+	}
+}
+
+func TestSplitRStarMinChildren(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for min := 1; min <= 5; min++ {
+		things := randomRects(rnd, 11)
+		entries := make([]entry, len(things))
+		for i, thing := range things {
+			entries[i] = entry{bb: thing.Bounds(), obj: thing}
+		}
+		n := &node{entries: entries, leaf: true, level: 1}
+
+		l, r := n.splitRStar(min)
+		if len(l.entries) < min || len(r.entries) < min {
+			t.Errorf("splitRStar(%d) returned groups of %d and %d entries", min, len(l.entries), len(r.entries))
+		}
+		var objs []Spatial
+		for _, e := range append(l.entries, r.entries...) {
+			objs = append(objs, e.obj)
+		}
+		ensureDisorderedSubset(t, objs, things)
+		if len(objs) != len(things) {
+			t.Errorf("splitRStar(%d) returned %d entries, expected %d", min, len(objs), len(things))
+		}
+	}
+}
+
+func TestRStarSplitStrategy(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+
+	rt := NewTree(2, 4, 10)
+	rt.SplitStrategy = RStarSplit
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+	verify(t, rt)
+	if level := underfull(rt.root, rt.MinChildren); level != 0 {
+		t.Errorf("underfull node at level %d", level)
+	}
+
+	bb := mustRect(Point{20, 20}, []float64{30, 30})
+	var expected []Spatial
+	for _, thing := range things {
+		if intersect(thing.Bounds(), bb) {
+			expected = append(expected, thing)
+		}
+	}
+	q := rt.SearchIntersect(bb)
+	ensureDisorderedSubset(t, q, expected)
+	if len(q) != len(expected) {
+		t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(expected))
+	}
+}
+
+// halfSplitter splits nodes in two halves along the first axis, counting its
+// calls.
+type halfSplitter struct {
+	calls int
+}
+
+func (s *halfSplitter) Split(bounds []Rect, minGroupSize int) []bool {
+	s.calls++
+	order := make([]int, len(bounds))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return bounds[order[i]].p[0] < bounds[order[j]].p[0] })
+	first := make([]bool, len(bounds))
+	for _, i := range order[:len(order)/2] {
+		first[i] = true
+	}
+	return first
+}
+
+// badSplitter puts every entry in the first group.
+type badSplitter struct{}
+
+func (badSplitter) Split(bounds []Rect, minGroupSize int) []bool {
+	first := make([]bool, len(bounds))
+	for i := range first {
+		first[i] = true
+	}
+	return first
+}
+
+// shortSplitter leaves the last entry out of both groups.
+type shortSplitter struct{}
+
+func (shortSplitter) Split(bounds []Rect, minGroupSize int) []bool {
+	first := make([]bool, len(bounds)-1)
+	for i := range first[:len(first)/2] {
+		first[i] = true
+	}
+	return first
+}
+
+func TestSplitter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 2000)
+	half := &halfSplitter{}
+
+	for _, tt := range []struct {
+		name     string
+		splitter Splitter
+	}{
+		{"QuadraticSplit", QuadraticSplit},
+		{"RStarSplit", RStarSplit},
+		{"LinearSplit", LinearSplit},
+		{"custom", half},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// start with the default strategy and swap in the splitter
+			rt := NewTree(2, 4, 10)
+			for _, thing := range things[:1000] {
+				rt.Insert(thing)
+			}
+			rt.Splitter = tt.splitter
+			for _, thing := range things[1000:] {
+				rt.Insert(thing)
+			}
+			if err := rt.Validate(); err != nil {
+				t.Fatalf("Validate() = %v", err)
+			}
+
+			bb := mustRect(Point{20, 20}, []float64{30, 30})
+			var expected []Spatial
+			for _, thing := range things {
+				if intersect(thing.Bounds(), bb) {
+					expected = append(expected, thing)
+				}
+			}
+			q := rt.SearchIntersect(bb)
+			ensureDisorderedSubset(t, q, expected)
+			if len(q) != len(expected) {
+				t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(expected))
+			}
+		})
+	}
+	if half.calls == 0 {
+		t.Errorf("the custom Splitter was never called")
+	}
+
+	// the built-in strategies split bounds directly
+	bounds := make([]Rect, 11)
+	for i, thing := range things[:len(bounds)] {
+		bounds[i] = thing.Bounds()
+	}
+	for _, s := range []SplitStrategy{QuadraticSplit, RStarSplit, LinearSplit} {
+		first := s.Split(bounds, 4)
+		n := 0
+		for _, f := range first {
+			if f {
+				n++
+			}
+		}
+		if len(first) != len(bounds) || n < 4 || len(bounds)-n < 4 {
+			t.Errorf("%v.Split() = %v, expected groups of at least 4 entries", s, first)
+		}
+	}
+
+	// invalid splits panic without modifying the node
+	rt := NewTree(2, 4, 10)
+	n := &node{leaf: true, level: 1}
+	for _, thing := range things[:11] {
+		n.entries = append(n.entries, entry{bb: thing.Bounds(), obj: thing})
+	}
+	orig := slices.Clone(n.entries)
+	for name, tt := range map[string]struct {
+		s        Splitter
+		expected SplitError
+	}{
+		"one group": {badSplitter{}, SplitError{Entries: 11, Assigned: 11, First: 11, MinChildren: 4}},
+		"too short": {shortSplitter{}, SplitError{Entries: 11, Assigned: 10, MinChildren: 4}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if err, ok := recover().(SplitError); !ok || err != tt.expected {
+					t.Errorf("splitWith() panicked with %v, expected %+v", err, tt.expected)
+				}
+				if !slices.EqualFunc(n.entries, orig, func(a, b entry) bool { return a.obj == b.obj }) {
+					t.Errorf("splitWith() modified the node before panicking")
+				}
+			}()
+			rt.splitWith(tt.s, n)
+		})
+	}
+
+	rt.Splitter = badSplitter{}
+	defer func() {
+		if _, ok := recover().(SplitError); !ok {
+			t.Errorf("Insert didn't panic with a SplitError with a Splitter returning an invalid split")
+		}
+	}()
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+}
+
 func TestAssignGroupLeastEnlargement(t *testing.T) {
 	r00 := entry{bb: mustRect(Point{0, 0}, []float64{1, 1})}
 	r01 := entry{bb: mustRect(Point{0, 1}, []float64{1, 1})}
@@ -359,10 +749,10 @@ func TestAdjustTreeNoPreviousSplit(t *testing.T) {
 	r01 := entry{bb: mustRect(Point{0, 1}, []float64{1, 1})}
 	r10 := entry{bb: mustRect(Point{1, 0}, []float64{1, 1})}
 	entries := []entry{r00, r01, r10}
-	n := node{rt.root, false, entries, 1}
-	rt.root.entries = []entry{{bb: Point{0, 0}.ToRect(0), child: &n}}
+	n := newNode(rt.root, false, entries, 1)
+	rt.root.entries = []entry{{bb: Point{0, 0}.ToRect(0), child: n}}
 
-	rt.adjustTree(&n, nil)
+	rt.adjustTree(n, nil)
 
 	e := rt.root.entries[0]
 	p, q := Point{0, 0}, Point{2, 2}
@@ -376,22 +766,22 @@ func TestAdjustTreeNoSplit(t *testing.T) {
 
 	r00 := entry{bb: mustRect(Point{0, 0}, []float64{1, 1})}
 	r01 := entry{bb: mustRect(Point{0, 1}, []float64{1, 1})}
-	left := node{rt.root, false, []entry{r00, r01}, 1}
-	leftEntry := entry{bb: Point{0, 0}.ToRect(0), child: &left}
+	left := newNode(rt.root, false, []entry{r00, r01}, 1)
+	leftEntry := entry{bb: Point{0, 0}.ToRect(0), child: left}
 
 	r10 := entry{bb: mustRect(Point{1, 0}, []float64{1, 1})}
 	r11 := entry{bb: mustRect(Point{1, 1}, []float64{1, 1})}
-	right := node{rt.root, false, []entry{r10, r11}, 1}
+	right := newNode(rt.root, false, []entry{r10, r11}, 1)
 
 	rt.root.entries = []entry{leftEntry}
-	retl, retr := rt.adjustTree(&left, &right)
+	retl, retr := rt.adjustTree(left, right)
 
 	if retl != rt.root || retr != nil {
 		t.Errorf("Expected adjustTree didn't split the root")
 	}
 
 	entries := rt.root.entries
-	if entries[0].child != &left || entries[1].child != &right {
+	if entries[0].child != left || entries[1].child != right {
 		t.Errorf("Expected adjustTree keeps left and adds n in parent")
 	}
 
@@ -409,15 +799,15 @@ func TestAdjustTreeSplitParent(t *testing.T) {
 
 	r00 := entry{bb: mustRect(Point{0, 0}, []float64{1, 1})}
 	r01 := entry{bb: mustRect(Point{0, 1}, []float64{1, 1})}
-	left := node{rt.root, false, []entry{r00, r01}, 1}
-	leftEntry := entry{bb: Point{0, 0}.ToRect(0), child: &left}
+	left := newNode(rt.root, false, []entry{r00, r01}, 1)
+	leftEntry := entry{bb: Point{0, 0}.ToRect(0), child: left}
 
 	r10 := entry{bb: mustRect(Point{1, 0}, []float64{1, 1})}
 	r11 := entry{bb: mustRect(Point{1, 1}, []float64{1, 1})}
-	right := node{rt.root, false, []entry{r10, r11}, 1}
+	right := newNode(rt.root, false, []entry{r10, r11}, 1)
 
 	rt.root.entries = []entry{leftEntry}
-	retl, retr := rt.adjustTree(&left, &right)
+	retl, retr := rt.adjustTree(left, right)
 
 	if len(retl.entries) != 1 || len(retr.entries) != 1 {
 		t.Errorf("Expected adjustTree distributed the entries")
@@ -446,6 +836,187 @@ func TestInsertRepeated(t *testing.T) {
 	}
 }
 
+func TestInsertOutsideEnlargesPath(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 200)
+	builds := map[string]func() *Rtree{
+		"dynamically built": func() *Rtree {
+			rt := NewTree(2, 3, 8)
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+			return rt
+		},
+		"bulk-loaded": func() *Rtree { return NewTree(2, 3, 8, things...) },
+		"R*-tree":     func() *Rtree { return NewTreeRStar(2, 3, 8, things...) },
+		"lazy bounds": func() *Rtree {
+			rt := NewTree(2, 3, 8)
+			rt.LazyBounds = true
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+			return rt
+		},
+	}
+	for name, build := range builds {
+		rt := build()
+		// outside of every existing bounding box, in a leaf with room left
+		outside := mustRect(Point{150, -50}, []float64{1, 1})
+		rt.Insert(&outside)
+
+		leaf := rt.findLeaf(rt.root, &outside, defaultComparator)
+		if leaf == nil {
+			t.Fatalf("%s: %v not found", name, outside)
+		}
+		for n := leaf; n.parent != nil; n = n.parent {
+			if bb := n.getEntry().bounds(); !bb.ContainsRect(outside) {
+				t.Errorf("%s: ancestor at level %d with bounding box %v doesn't contain %v", name, n.level, bb, outside)
+			}
+		}
+
+		results := rt.SearchIntersect(mustRect(Point{149, -51}, []float64{3, 3}))
+		if len(results) != 1 || results[0] != &outside {
+			t.Errorf("%s: SearchIntersect() around %v = %v", name, outside, results)
+		}
+	}
+}
+
+func TestInsertDimError(t *testing.T) {
+	rt := NewTree(2, 3, 5, randomRects(rand.New(rand.NewSource(1)), 20)...)
+	var events []MutationEvent
+	rt.OnMutation(func(ev MutationEvent) {
+		events = append(events, ev)
+	})
+
+	err := rt.Insert(mustRect(Point{0, 0, 0}, []float64{1, 1, 1}))
+	dimErr, ok := err.(*DimError)
+	if !ok {
+		t.Fatalf("Insert() = %v, expected a *DimError", err)
+	}
+	if dimErr.Expected != 2 || dimErr.Actual != 3 {
+		t.Errorf("Insert() = %+v, expected Expected = 2 and Actual = 3", *dimErr)
+	}
+	if rt.Size() != 20 {
+		t.Errorf("Size() = %d after a failed Insert, expected 20", rt.Size())
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for a failed Insert, got %v", events)
+	}
+	verify(t, rt)
+}
+
+func TestInsertNonFinite(t *testing.T) {
+	rt := NewTree(2, 3, 5, randomRects(rand.New(rand.NewSource(1)), 20)...)
+	var events []MutationEvent
+	rt.OnMutation(func(ev MutationEvent) {
+		events = append(events, ev)
+	})
+
+	panics := func(f func()) (panicked bool) {
+		defer func() { panicked = recover() != nil }()
+		f()
+		return false
+	}
+	others := randomRects(rand.New(rand.NewSource(2)), 20)
+
+	nan := Rect{Point{0, math.NaN()}, Point{1, 1}}
+	inf := Rect{Point{0, 0}, Point{math.Inf(1), 1}}
+	for _, bb := range []Rect{nan, inf} {
+		if err := rt.Insert(bb); err == nil {
+			t.Errorf("Insert(%v) = nil, expected an error", bb)
+		}
+		if err := rt.InsertBatch([]Spatial{mustRect(Point{0, 0}, []float64{1, 1}), bb}); err == nil {
+			t.Errorf("InsertBatch() = nil with %v, expected an error", bb)
+		}
+		obj := rt.GetAll()[0]
+		if _, err := rt.Update(obj, bb); err == nil {
+			t.Errorf("Update(%v) = nil, expected an error", bb)
+		}
+		if _, err := NewTreeChecked(2, 3, 5, bb); err == nil {
+			t.Errorf("NewTreeChecked() = nil with %v, expected an error", bb)
+		}
+		if !panics(func() { rt.Load(mustRect(Point{0, 0}, []float64{1, 1}), bb) }) {
+			t.Errorf("Load() didn't panic with %v", bb)
+		}
+		if !panics(func() { NewTree(2, 3, 5, bb) }) {
+			t.Errorf("NewTree() didn't panic with %v", bb)
+		}
+		if !panics(func() { NewTree(2, 3, 5, append(others, bb)...) }) {
+			t.Errorf("NewTree() didn't panic with %v among %d objects", bb, len(others)+1)
+		}
+		if !panics(func() { NewTreeCanonical(2, 3, 5, append([]Spatial{bb}, others...)) }) {
+			t.Errorf("NewTreeCanonical() didn't panic with %v", bb)
+		}
+	}
+	if rt.Size() != 20 {
+		t.Errorf("Size() = %d after failed inserts, expected 20", rt.Size())
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for failed inserts, got %v", events)
+	}
+	verify(t, rt)
+}
+
+// pointerThing stores its bounds behind a pointer, which may be nil.
+type pointerThing struct {
+	where *Rect
+}
+
+func (t *pointerThing) Bounds() Rect {
+	return *t.where
+}
+
+func TestInsertInvalidObject(t *testing.T) {
+	rt := NewTree(2, 3, 5, randomRects(rand.New(rand.NewSource(1)), 20)...)
+	var events []MutationEvent
+	rt.OnMutation(func(ev MutationEvent) {
+		events = append(events, ev)
+	})
+
+	for name, obj := range map[string]Spatial{
+		"nil":          nil,
+		"nil bounds":   &pointerThing{},
+		"nil pointer":  (*pointerThing)(nil),
+		"empty bounds": Rect{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := rt.Insert(obj); err == nil {
+				t.Errorf("Insert(%v) = nil, expected an error", obj)
+			}
+			if _, err := rt.InsertHandle(obj); err == nil {
+				t.Errorf("InsertHandle(%v) = nil, expected an error", obj)
+			}
+			if err := rt.InsertBatch([]Spatial{mustRect(Point{0, 0}, []float64{1, 1}), obj}); err == nil {
+				t.Errorf("InsertBatch() = nil with %v, expected an error", obj)
+			}
+			if _, err := NewTreeChecked(2, 3, 5, obj); err == nil {
+				t.Errorf("NewTreeChecked() = nil with %v, expected an error", obj)
+			}
+			func() {
+				defer func() {
+					if _, ok := recover().(error); !ok {
+						t.Errorf("NewTreeCanonical() didn't panic with an error for %v", obj)
+					}
+				}()
+				NewTreeCanonical(2, 3, 5, []Spatial{obj})
+			}()
+			if rt.Delete(obj) {
+				t.Errorf("Delete(%v) = true, expected false", obj)
+			}
+			bb := mustRect(Point{0, 0}, []float64{1, 1})
+			if _, err := rt.Update(obj, bb); err == nil {
+				t.Errorf("Update(%v) = nil, expected an error", obj)
+			}
+		})
+	}
+	if rt.Size() != 20 {
+		t.Errorf("Size() = %d after failed inserts, expected 20", rt.Size())
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no events for failed inserts, got %v", events)
+	}
+	verify(t, rt)
+}
+
 func TestInsertNoSplit(t *testing.T) {
 	rt := NewTree(2, 3, 3)
 	thing := mustRect(Point{0, 0}, []float64{2, 1})
@@ -598,6 +1169,20 @@ func TestBulkLoadingValidity(t *testing.T) {
 	}
 }
 
+func TestBulkLoadValidate(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 600)
+
+	for _, c := range []struct{ min, max int }{{1, 2}, {2, 3}, {2, 4}, {3, 8}, {5, 9}, {10, 20}} {
+		for n := 1; n <= len(things); n++ {
+			rt := NewTree(2, c.min, c.max, things[:n]...)
+			if err := rt.Validate(); err != nil {
+				t.Fatalf("NewTree(2, %d, %d) with %d objects: %v", c.min, c.max, n, err)
+			}
+		}
+	}
+}
+
 func TestFindLeaf(t *testing.T) {
 	rt := NewTree(2, 3, 3)
 	rects := []Rect{
@@ -776,6 +1361,43 @@ func TestDeleteFlatten(t *testing.T) {
 	}
 }
 
+func TestContains(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 300)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for _, thing := range things {
+				if !rt.Contains(thing) {
+					t.Fatalf("Contains(%v) = false for a stored object", thing)
+				}
+			}
+
+			// an equal object stored separately isn't matched
+			r := mustRect(Point{1, 2}, []float64{3, 4})
+			twin := r
+			if err := rt.Insert(&r); err != nil {
+				t.Fatal(err)
+			}
+			if !rt.Contains(&r) {
+				t.Errorf("Contains(%v) = false after Insert", &r)
+			}
+			if rt.Contains(&twin) {
+				t.Errorf("Contains(%v) = true for a copy of a stored object", &twin)
+			}
+			if !rt.Delete(&r) {
+				t.Fatalf("failed to delete %v", &r)
+			}
+			if rt.Contains(&r) {
+				t.Errorf("Contains(%v) = true after Delete", &r)
+			}
+			if rt.Contains(nil) {
+				t.Errorf("Contains(nil) = true")
+			}
+		})
+	}
+}
+
 func TestDelete(t *testing.T) {
 	rects := []Rect{
 		mustRect(Point{0, 0}, []float64{2, 1}),
@@ -824,31 +1446,316 @@ func TestDelete(t *testing.T) {
 	}
 }
 
-func TestDeleteWithDepthChange(t *testing.T) {
+func TestDeleteIdenticalBounds(t *testing.T) {
+	bb := mustRect(Point{5, 5}, []float64{1, 1})
+	a, b := &bb, &Rect{}
+	*b = bb
+
 	rt := NewTree(2, 3, 3)
-	rects := []Rect{
-		mustRect(Point{0, 0}, []float64{2, 1}),
-		mustRect(Point{3, 1}, []float64{1, 2}),
-		mustRect(Point{1, 2}, []float64{2, 2}),
-		mustRect(Point{8, 6}, []float64{1, 1}),
+	rt.Insert(a)
+	rt.Insert(b)
+	if !rt.Delete(b) {
+		t.Fatalf("failed to delete %v", b)
 	}
-	things := []Spatial{}
-	for i := range rects {
-		things = append(things, &rects[i])
+	if all := rt.GetAll(); len(all) != 1 || all[0] != a {
+		t.Errorf("GetAll() = %v after deleting one of two identical objects, expected %v", all, a)
 	}
 
-	for _, thing := range things {
-		rt.Insert(thing)
+	// identical objects spread across several leaves
+	rects := make([]Rect, 50)
+	things := make([]Spatial, len(rects))
+	for i := range rects {
+		rects[i] = bb
+		things[i] = &rects[i]
+	}
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := len(things) - 1; i >= 0; i -= 2 {
+				if !rt.Delete(things[i]) {
+					t.Fatalf("failed to delete object %d", i)
+				}
+				if rt.Delete(things[i]) {
+					t.Fatalf("deleted object %d twice", i)
+				}
+			}
+			verify(t, rt)
+			remaining := rt.GetAll()
+			if len(remaining) != len(things)/2 {
+				t.Fatalf("%d objects remaining, expected %d", len(remaining), len(things)/2)
+			}
+			for _, obj := range remaining {
+				i := slices.Index(things, obj)
+				if i < 0 || i%2 != 0 {
+					t.Errorf("unexpected object %d remaining", i)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteCondenseLevels(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, rt := range []*Rtree{NewTree(2, 2, 4), NewTreeRStar(2, 2, 4)} {
+		things := randomRects(rnd, 300)
+		for _, thing := range things {
+			rt.Insert(thing)
+		}
+		depth := rt.Depth()
+
+		// deleting the objects from left to right empties whole subtrees
+		sort.Slice(things, func(i, j int) bool {
+			return things[i].Bounds().p[0] < things[j].Bounds().p[0]
+		})
+		multiLevel := false
+		for i, thing := range things {
+			if !rt.Delete(thing) {
+				t.Fatalf("failed to delete %v", thing)
+			}
+			if len(rt.deleted) > 1 {
+				multiLevel = true
+			}
+			if err := rt.Validate(); err != nil {
+				t.Fatalf("Validate() = %v after %d deletions", err, i+1)
+			}
+			if rt.Size() != len(things)-i-1 {
+				t.Fatalf("Size() = %d after %d deletions", rt.Size(), i+1)
+			}
+			if i == len(things)/2 {
+				bb := mustRect(Point{-10, -10}, []float64{200, 200})
+				ensureDisorderedSubset(t, rt.SearchIntersect(bb), things[i+1:])
+				if n := len(rt.SearchIntersect(bb)); n != len(things)-i-1 {
+					t.Errorf("SearchIntersect returned %d objects after %d deletions", n, i+1)
+				}
+			}
+		}
+		if !multiLevel {
+			t.Errorf("no deletion removed nodes at several levels")
+		}
+		if rt.Depth() != 1 || depth == 1 {
+			t.Errorf("depth %d after deleting everything, %d before", rt.Depth(), depth)
+		}
+	}
+}
+
+func TestDeleteHandle(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	others := randomRects(rnd, 1000)
+
+	for _, rt := range []*Rtree{NewTree(2, 3, 8), NewTreeRStar(2, 3, 8)} {
+		handles := make([]*Entry, len(things))
+		for i, thing := range things {
+			h, err := rt.InsertHandle(thing)
+			if err != nil {
+				t.Fatalf("InsertHandle() = %v", err)
+			}
+			if h.Object() != thing {
+				t.Fatalf("handle references %v, expected %v", h.Object(), thing)
+			}
+			if !rt.rstar && (h.leaf == nil || !h.leaf.holds(entry{bb: thing.Bounds()})) {
+				t.Fatalf("handle doesn't record the leaf holding %v", thing)
+			}
+			handles[i] = h
+		}
+		// unrelated inserts move some objects to other leaves
+		for _, thing := range others {
+			rt.Insert(thing)
+		}
+
+		for i, h := range handles[:500] {
+			if !rt.DeleteHandle(h) {
+				t.Fatalf("DeleteHandle() = false for object %d", i)
+			}
+			if rt.DeleteHandle(h) {
+				t.Errorf("DeleteHandle() = true for object %d deleted already", i)
+			}
+		}
+		if err := rt.Validate(); err != nil {
+			t.Errorf("Validate() = %v after deleting through handles", err)
+		}
+		if rt.Size() != len(things)+len(others)-500 {
+			t.Errorf("Size() = %d, expected %d", rt.Size(), len(things)+len(others)-500)
+		}
+		all := rt.GetAll()
+		for _, thing := range things[:500] {
+			if slices.Contains(all, thing) {
+				t.Fatalf("%v still in the tree after DeleteHandle", thing)
+			}
+		}
+
+		// handles survive a rebuild and objects deleted otherwise
+		rt.Rebuild()
+		rt.Delete(things[500])
+		if rt.DeleteHandle(handles[500]) {
+			t.Errorf("DeleteHandle() = true for an object removed by Delete")
+		}
+		for _, h := range handles[501:] {
+			if !rt.DeleteHandle(h) {
+				t.Fatalf("DeleteHandle() = false after Rebuild")
+			}
+		}
+		if err := rt.Validate(); err != nil {
+			t.Errorf("Validate() = %v after deleting through handles", err)
+		}
+		if !rt.EqualContents(NewTree(2, 3, 8, others...), nil) {
+			t.Errorf("the tree doesn't hold the other objects only after deleting through handles")
+		}
+	}
+
+	rt := NewTree(2, 3, 8)
+	if _, err := rt.InsertHandle(mustRect(Point{0, 0, 0}, []float64{1, 1, 1})); err == nil {
+		t.Errorf("InsertHandle() = nil with an object of the wrong dimension")
+	}
+	if rt.DeleteHandle(nil) {
+		t.Errorf("DeleteHandle(nil) = true")
+	}
+}
+
+func TestDeleteMatching(t *testing.T) {
+	type IDRect struct {
+		ID int
+		Rect
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i, thing := range randomRects(rnd, 500) {
+		things = append(things, &IDRect{i, *thing.(*Rect)})
+	}
+	even := func(obj Spatial) bool { return obj.(*IDRect).ID%2 == 0 }
+
+	for _, tc := range tests(2, 3, 6, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			deleted := 0
+			rt.OnMutation(func(ev MutationEvent) {
+				if ev.Kind == DeleteMutation {
+					deleted++
+				}
+			})
+
+			if n := rt.DeleteMatching(even); n != len(things)/2 {
+				t.Errorf("DeleteMatching() = %d, expected %d", n, len(things)/2)
+			}
+			verify(t, rt)
+			checkCounts(t, rt.root)
+			if rt.Size() != len(things)/2 {
+				t.Errorf("Size() = %d, expected %d", rt.Size(), len(things)/2)
+			}
+			if deleted != len(things)/2 {
+				t.Errorf("%d delete events, expected %d", deleted, len(things)/2)
+			}
+
+			all := mustRect(Point{-10, -10}, []float64{200, 200})
+			results := rt.SearchIntersect(all)
+			if len(results) != len(things)/2 {
+				t.Errorf("SearchIntersect returned %d objects, expected %d", len(results), len(things)/2)
+			}
+			for _, obj := range results {
+				if even(obj) {
+					t.Errorf("object %d wasn't deleted", obj.(*IDRect).ID)
+				}
+			}
+			for i := 0; i < 20; i++ {
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{10, 10})
+				var expected []Spatial
+				for _, thing := range things {
+					if !even(thing) && intersect(thing.Bounds(), bb) {
+						expected = append(expected, thing)
+					}
+				}
+				if q := rt.SearchIntersect(bb); len(q) != len(expected) {
+					t.Errorf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(q), len(expected))
+				}
+			}
+
+			if n := rt.DeleteMatching(even); n != 0 {
+				t.Errorf("DeleteMatching() = %d on a tree without matching objects", n)
+			}
+			if n := rt.DeleteMatching(func(Spatial) bool { return true }); n != len(things)/2 {
+				t.Errorf("DeleteMatching() = %d, expected %d", n, len(things)/2)
+			}
+			if rt.Size() != 0 || rt.Depth() != 1 {
+				t.Errorf("Size() = %d and Depth() = %d after deleting everything", rt.Size(), rt.Depth())
+			}
+			rt.Insert(things[0])
+			verify(t, rt)
+		})
+	}
+
+	// dynamically built trees remain valid
+	rt := NewTree(2, 3, 6)
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+	rt.DeleteMatching(func(obj Spatial) bool { return obj.(*IDRect).ID%3 != 0 })
+	if err := rt.Validate(); err != nil {
+		t.Errorf("Validate() = %v after DeleteMatching", err)
+	}
+}
+
+func TestDeleteWithDepthChange(t *testing.T) {
+	rt := NewTree(2, 3, 3)
+	rects := []Rect{
+		mustRect(Point{0, 0}, []float64{2, 1}),
+		mustRect(Point{3, 1}, []float64{1, 2}),
+		mustRect(Point{1, 2}, []float64{2, 2}),
+		mustRect(Point{8, 6}, []float64{1, 1}),
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+
+	// delete last item and condense nodes
+	rt.Delete(things[3])
+
+	// rt.height should be 1 otherwise insert increases height to 3
+	rt.Insert(things[3])
+
+	// and verify would fail
+	verify(t, rt)
+}
+
+func TestDeleteWithComparatorCandidates(t *testing.T) {
+	type IDRect struct {
+		ID int
+		Rect
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i, thing := range randomRects(rnd, 200) {
+		things = append(things, &IDRect{i, *thing.(*Rect)})
+	}
+
+	for _, tc := range tests(2, 3, 6, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			for _, thing := range things {
+				// a reconstructed object, equal to the stored one by ID only
+				target := &IDRect{thing.(*IDRect).ID, thing.(*IDRect).Rect}
+				cmp := func(obj1, obj2 Spatial) bool {
+					if !obj1.Bounds().ContainsRect(target.Bounds()) {
+						t.Fatalf("cmp called on %v, which doesn't contain %v", obj1, target)
+					}
+					return obj1.(*IDRect).ID == obj2.(*IDRect).ID
+				}
+				if !rt.DeleteWithComparator(target, cmp) {
+					t.Fatalf("failed to delete %v", target)
+				}
+			}
+			if rt.Size() != 0 {
+				t.Errorf("Size() = %d after deleting every object", rt.Size())
+			}
+		})
 	}
-
-	// delete last item and condense nodes
-	rt.Delete(things[3])
-
-	// rt.height should be 1 otherwise insert increases height to 3
-	rt.Insert(things[3])
-
-	// and verify would fail
-	verify(t, rt)
 }
 
 func TestDeleteWithComparator(t *testing.T) {
@@ -940,6 +1847,102 @@ func TestDeleteThenInsert(t *testing.T) {
 	}
 }
 
+type movingThing struct {
+	bb Rect
+}
+
+func (m *movingThing) Bounds() Rect {
+	return m.bb
+}
+
+func TestUpdateInPlace(t *testing.T) {
+	var things []Spatial
+	for _, thing := range randomRects(rand.New(rand.NewSource(1)), 100) {
+		things = append(things, &movingThing{*thing.(*Rect)})
+	}
+	rt := NewTree(2, 3, 6, things...)
+
+	// shrinking an object keeps it within its leaf
+	m := things[10].(*movingThing)
+	leaf := rt.findLeaf(rt.root, m, defaultComparator)
+	newBounds := mustRect(Point{m.bb.p[0], m.bb.p[1]}, []float64{0.05, 0.05})
+	found, err := rt.Update(m, newBounds)
+	if !found || err != nil {
+		t.Fatalf("Update() = %v, %v, expected true, nil", found, err)
+	}
+	m.bb = newBounds
+
+	if rt.findLeaf(rt.root, m, defaultComparator) != leaf {
+		t.Errorf("object moved to another leaf")
+	}
+	verify(t, rt)
+	if rt.Size() != len(things) {
+		t.Errorf("Size() = %d after Update, expected %d", rt.Size(), len(things))
+	}
+}
+
+func TestUpdateReinsert(t *testing.T) {
+	var things []Spatial
+	for _, thing := range randomRects(rand.New(rand.NewSource(1)), 100) {
+		things = append(things, &movingThing{*thing.(*Rect)})
+	}
+
+	for _, tc := range tests(2, 3, 6, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			var events []MutationEvent
+			rt.OnMutation(func(ev MutationEvent) {
+				if ev.Kind != SplitMutation {
+					events = append(events, ev)
+				}
+			})
+
+			m := things[10].(*movingThing)
+			oldBounds := m.bb
+			newBounds := mustRect(Point{500, 500}, []float64{1, 1})
+			found, err := rt.Update(m, newBounds)
+			if !found || err != nil {
+				t.Fatalf("Update() = %v, %v, expected true, nil", found, err)
+			}
+			m.bb = newBounds
+
+			verify(t, rt)
+			checkCounts(t, rt.root)
+			if rt.Size() != len(things) {
+				t.Errorf("Size() = %d after Update, expected %d", rt.Size(), len(things))
+			}
+			if results := rt.SearchIntersect(newBounds); len(results) != 1 || results[0] != m {
+				t.Errorf("SearchIntersect(%v) = %v, expected the moved object", newBounds, results)
+			}
+			if contains(m, rt.SearchIntersect(oldBounds)) {
+				t.Errorf("moved object still found at %v", oldBounds)
+			}
+			if len(events) != 1 || events[0].Kind != UpdateMutation || events[0].Object != m ||
+				!events[0].Bounds[0].Equal(oldBounds) || !events[0].Bounds[1].Equal(newBounds) {
+				t.Errorf("expected a single update event, got %v", events)
+			}
+			m.bb = oldBounds
+		})
+	}
+}
+
+func TestUpdateNotFound(t *testing.T) {
+	rt := NewTree(2, 3, 6, randomRects(rand.New(rand.NewSource(1)), 20)...)
+	newBounds := mustRect(Point{0, 0}, []float64{1, 1})
+
+	if found, err := rt.Update(&movingThing{newBounds}, newBounds); found || err != nil {
+		t.Errorf("Update() = %v, %v for a missing object, expected false, nil", found, err)
+	}
+
+	bb3 := mustRect(Point{0, 0, 0}, []float64{1, 1, 1})
+	for _, bb := range []Rect{bb3, {}} {
+		_, err := rt.Update(&movingThing{newBounds}, bb)
+		if _, ok := err.(*DimError); !ok {
+			t.Errorf("Update(%v) = %v, expected a *DimError", bb, err)
+		}
+	}
+}
+
 func TestSearchIntersect(t *testing.T) {
 	rects := []Rect{
 		mustRect(Point{0, 0}, []float64{2, 1}),
@@ -967,7 +1970,7 @@ func TestSearchIntersect(t *testing.T) {
 			q := rt.SearchIntersect(bb)
 
 			var expected []Spatial
-			for _, i := range []int{1, 2, 3, 4, 6, 7} {
+			for _, i := range []int{1, 2, 3, 4, 5, 6, 7} {
 				expected = append(expected, things[i])
 			}
 
@@ -1003,7 +2006,7 @@ func TestSearchIntersectWithLimit(t *testing.T) {
 
 			// expected contains all the intersecting things
 			var expected []Spatial
-			for _, i := range []int{1, 2, 6, 7, 3, 4} {
+			for _, i := range []int{1, 2, 6, 7, 3, 4, 5} {
 				expected = append(expected, things[i])
 			}
 
@@ -1037,96 +2040,477 @@ func TestSearchIntersectWithLimit(t *testing.T) {
 	}
 }
 
-func TestSearchIntersectWithTestFilter(t *testing.T) {
-	rects := []Rect{
-		mustRect(Point{0, 0}, []float64{2, 1}),
-		mustRect(Point{3, 1}, []float64{1, 2}),
-		mustRect(Point{1, 2}, []float64{2, 2}),
-		mustRect(Point{8, 6}, []float64{1, 1}),
-		mustRect(Point{10, 3}, []float64{1, 2}),
-		mustRect(Point{11, 7}, []float64{1, 1}),
-		mustRect(Point{2, 6}, []float64{1, 2}),
-		mustRect(Point{3, 6}, []float64{1, 2}),
-		mustRect(Point{2, 8}, []float64{1, 2}),
-		mustRect(Point{3, 8}, []float64{1, 2}),
+func TestSearchIntersectWithLimitRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			bb := mustRect(Point{10, 10}, []float64{60, 60})
+			all := rt.SearchIntersect(bb)
+
+			if q := rt.SearchIntersectWithLimit(-1, bb); len(q) != len(all) {
+				t.Errorf("SearchIntersectWithLimit(-1) returned %d objects, expected %d", len(q), len(all))
+			}
+			for _, k := range []int{0, 1, 5, 20, len(all), len(all) + 10} {
+				q := rt.SearchIntersectWithLimit(k, bb)
+				if len(q) > k {
+					t.Errorf("SearchIntersectWithLimit(%d) returned %d objects", k, len(q))
+				}
+				ensureDisorderedSubset(t, q, all)
+			}
+
+			// the search stops as soon as the limit is reached
+			calls := 0
+			counter := func(results []Spatial, object Spatial) (bool, bool) {
+				calls++
+				return false, false
+			}
+			rt.SearchIntersect(bb, counter, LimitFilter(5))
+			if calls != 6 {
+				t.Errorf("filters called on %d objects with a limit of 5, expected 6", calls)
+			}
+		})
 	}
-	things := []Spatial{}
-	for i := range rects {
-		things = append(things, &rects[i])
+}
+
+func TestSearchIntersectWithFilter(t *testing.T) {
+	type IDRect struct {
+		ID int
+		Rect
 	}
 
-	for _, tc := range tests(2, 3, 3, things...) {
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i, thing := range randomRects(rnd, 300) {
+		things = append(things, &IDRect{i, *thing.(*Rect)})
+	}
+
+	for _, tc := range tests(2, 3, 8, things...) {
 		t.Run(tc.name, func(t *testing.T) {
 			rt := tc.build()
+			bb := mustRect(Point{10, 10}, []float64{60, 60})
 
-			bb := mustRect(Point{2, 1.5}, []float64{10, 5.5})
-
-			// intersecting indexes are 1, 2, 6, 7, 3, 4
-			// rects which we do not filter out
 			var expected []Spatial
-			for _, i := range []int{1, 6, 4} {
-				expected = append(expected, things[i])
+			for _, obj := range rt.SearchIntersect(bb) {
+				if obj.(*IDRect).ID%2 == 0 {
+					expected = append(expected, obj)
+				}
 			}
 
-			// this test filter will only pick the objects that are in expected
-			objects := rt.SearchIntersect(bb, func(results []Spatial, object Spatial) (bool, bool) {
-				for _, exp := range expected {
-					if exp == object {
-						return false, false
-					}
+			q := rt.SearchIntersectWithFilter(bb, func(obj Spatial) bool {
+				if !intersect(obj.Bounds(), bb) {
+					t.Errorf("filter called on %v, which doesn't intersect %v", obj, bb)
 				}
-				return true, false
+				return obj.(*IDRect).ID%2 == 0
 			})
-
-			ensureDisorderedSubset(t, objects, expected)
+			if len(q) != len(expected) {
+				t.Errorf("SearchIntersectWithFilter() returned %d objects, expected %d", len(q), len(expected))
+			}
+			ensureDisorderedSubset(t, q, expected)
 		})
 	}
 }
 
-func TestSearchIntersectNoResults(t *testing.T) {
-	things := []Spatial{
-		mustRect(Point{0, 0}, []float64{2, 1}),
-		mustRect(Point{3, 1}, []float64{1, 2}),
-		mustRect(Point{1, 2}, []float64{2, 2}),
-		mustRect(Point{8, 6}, []float64{1, 1}),
-		mustRect(Point{10, 3}, []float64{1, 2}),
-		mustRect(Point{11, 7}, []float64{1, 1}),
-		mustRect(Point{2, 6}, []float64{1, 2}),
-		mustRect(Point{3, 6}, []float64{1, 2}),
-		mustRect(Point{2, 8}, []float64{1, 2}),
-		mustRect(Point{3, 8}, []float64{1, 2}),
+func TestSearchWithinRadius(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i := 0; i < 500; i++ {
+		r := Point{rnd.Float64() * 100, rnd.Float64() * 100}.ToRect(0)
+		things = append(things, &r)
 	}
+	things = append(things, randomRects(rnd, 100)...)
 
-	for _, tc := range tests(2, 3, 3, things...) {
+	for _, tc := range tests(2, 3, 8, things...) {
 		t.Run(tc.name, func(t *testing.T) {
 			rt := tc.build()
+			for i := 0; i < 50; i++ {
+				p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+				radius := rnd.Float64() * 20
+
+				var expected []Spatial
+				for _, thing := range things {
+					if p.minDist(thing.Bounds()) <= radius*radius {
+						expected = append(expected, thing)
+					}
+				}
 
-			bb := mustRect(Point{99, 99}, []float64{10, 5.5})
-			q := rt.SearchIntersect(bb)
-			if len(q) != 0 {
-				t.Errorf("SearchIntersect failed to return nil slice on failing query")
+				results := rt.SearchWithinRadius(p, radius)
+				if len(results) != len(expected) {
+					t.Errorf("SearchWithinRadius(%v, %v) returned %d objects, expected %d", p, radius, len(results), len(expected))
+				}
+				ensureDisorderedSubset(t, results, expected)
 			}
 		})
 	}
 }
 
-func TestSortEntries(t *testing.T) {
-	objs := []Rect{
-		mustRect(Point{1, 1}, []float64{1, 1}),
-		mustRect(Point{2, 2}, []float64{1, 1}),
-		mustRect(Point{3, 3}, []float64{1, 1})}
-	entries := []entry{
-		{objs[2], nil, &objs[2]},
-		{objs[1], nil, &objs[1]},
-		{objs[0], nil, &objs[0]},
-	}
-	sorted, dists := sortEntries(Point{0, 0}, entries)
-	if !entryEq(sorted[0], entries[2]) || !entryEq(sorted[1], entries[1]) || !entryEq(sorted[2], entries[0]) {
-		t.Errorf("sortEntries failed")
-	}
-	if dists[0] != 2 || dists[1] != 8 || dists[2] != 18 {
-		t.Errorf("sortEntries failed to calculate proper distances")
-	}
+func TestSearchWithinRadiusNegative(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(DistError); !ok {
+			t.Errorf("expected a DistError panic")
+		}
+	}()
+	NewTree(2, 3, 8).SearchWithinRadius(Point{0, 0}, -1)
+}
+
+func TestForEachInRect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+	value := make(map[Spatial]float64, len(things))
+	for _, thing := range things {
+		value[thing] = rnd.Float64()
+	}
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 20; i++ {
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{20, 20})
+				expected := 0.0
+				for _, obj := range rt.SearchIntersect(bb) {
+					expected += value[obj]
+				}
+				sum := 0.0
+				rt.ForEachInRect(bb, func(obj Spatial) bool {
+					sum += value[obj]
+					return true
+				})
+				if sum != expected {
+					t.Errorf("ForEachInRect(%v) summed %v, expected %v", bb, sum, expected)
+				}
+			}
+
+			// stop after the third object
+			all := mustRect(Point{-10, -10}, []float64{200, 200})
+			var visited []Spatial
+			rt.ForEachInRect(all, func(obj Spatial) bool {
+				visited = append(visited, obj)
+				return len(visited) < 3
+			})
+			if expected := rt.SearchIntersect(all)[:3]; !slices.Equal(visited, expected) {
+				t.Errorf("ForEachInRect visited %v before stopping, expected %v", visited, expected)
+			}
+		})
+	}
+}
+
+func TestReduceIntersect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 20; i++ {
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{20, 20})
+				expected := 0.0
+				for _, obj := range rt.SearchIntersect(bb) {
+					expected += obj.Bounds().Size()
+				}
+				area := ReduceIntersect(rt, bb, 0.0, func(acc float64, obj Spatial) float64 {
+					return acc + obj.Bounds().Size()
+				})
+				if area != expected {
+					t.Errorf("ReduceIntersect(%v) summed an area of %v, expected %v", bb, area, expected)
+				}
+			}
+
+			empty := mustRect(Point{-50, -50}, []float64{1, 1})
+			if n := ReduceIntersect(rt, empty, -1, func(acc int, obj Spatial) int { return acc + 1 }); n != -1 {
+				t.Errorf("ReduceIntersect(%v) = %d, expected the initial value -1", empty, n)
+			}
+		})
+	}
+}
+
+func TestSearchIntersectBoxes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 20; i++ {
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{20, 20})
+				results := rt.SearchIntersectBoxes(bb)
+				var objs []Spatial
+				for _, r := range results {
+					if !r.Bounds.Equal(r.Object.Bounds()) {
+						t.Errorf("SearchIntersectBoxes(%v) returned bounds %v for %v", bb, r.Bounds, r.Object)
+					}
+					objs = append(objs, r.Object)
+				}
+				if expected := rt.SearchIntersect(bb); !slices.Equal(objs, expected) {
+					t.Errorf("SearchIntersectBoxes(%v) returned %v, expected %v", bb, objs, expected)
+				}
+			}
+
+			// modifying the boxes doesn't change the tree
+			all := mustRect(Point{-10, -10}, []float64{200, 200})
+			original := rt.String()
+			for _, r := range rt.SearchIntersectBoxes(all) {
+				r.Bounds.p[0], r.Bounds.q[0] = -1, 1e9
+			}
+			if rt.String() != original {
+				t.Errorf("modifying the boxes returned by SearchIntersectBoxes changed the tree")
+			}
+		})
+	}
+}
+
+func TestIntersects(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 200)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			examined := 0
+			counter := func(results []Spatial, obj Spatial) (refuse, abort bool) {
+				examined++
+				return false, false
+			}
+			all := mustRect(Point{-10, -10}, []float64{200, 200})
+			if !rt.Intersects(all, counter) {
+				t.Errorf("Intersects(%v) = false", all)
+			}
+			if examined != 1 {
+				t.Errorf("Intersects(%v) examined %d objects, expected to stop at the first", all, examined)
+			}
+
+			// refused objects don't stop the search
+			examined = 0
+			refuseFive := func(results []Spatial, obj Spatial) (refuse, abort bool) {
+				return examined <= 5, false
+			}
+			if !rt.Intersects(all, counter, refuseFive) || examined != 6 {
+				t.Errorf("Intersects(%v) examined %d objects, expected 6", all, examined)
+			}
+
+			for i := 0; i < 50; i++ {
+				bb := mustRect(Point{rnd.Float64()*120 - 10, rnd.Float64()*120 - 10}, []float64{rnd.Float64() * 5, rnd.Float64() * 5})
+				if found, expected := rt.Intersects(bb), len(rt.SearchIntersect(bb)) > 0; found != expected {
+					t.Errorf("Intersects(%v) = %v, expected %v", bb, found, expected)
+				}
+			}
+			outside := mustRect(Point{500, 500}, []float64{1, 1})
+			if rt.Intersects(outside) {
+				t.Errorf("Intersects(%v) = true", outside)
+			}
+		})
+	}
+}
+
+func TestEstimateIntersect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 2000)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			var interior func(n *node) int
+			interior = func(n *node) int {
+				if n.leaf {
+					return 0
+				}
+				count := 1
+				for _, e := range n.entries {
+					count += interior(e.child)
+				}
+				return count
+			}
+			nodes := interior(rt.root)
+
+			for i := 0; i < 10; i++ {
+				p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+				prev := 0
+				for _, tol := range []float64{0, 1, 2, 5, 10, 20, 50, 200} {
+					est := rt.EstimateIntersect(p.ToRect(tol))
+					if est < prev {
+						t.Errorf("EstimateIntersect() = %d around %v with tolerance %v, less than %d for a smaller box", est, p, tol, prev)
+					}
+					prev = est
+				}
+				// the largest box covers the whole tree
+				if prev != nodes {
+					t.Errorf("EstimateIntersect() = %d for a box covering the tree, expected %d interior nodes", prev, nodes)
+				}
+			}
+
+			outside := mustRect(Point{500, 500}, []float64{1, 1})
+			if est := rt.EstimateIntersect(outside); est != 0 {
+				t.Errorf("EstimateIntersect() = %d outside of the tree, expected 0", est)
+			}
+		})
+	}
+
+	rt := NewTree(2, 3, 8, things[:5]...)
+	if est := rt.EstimateIntersect(mustRect(Point{0, 0}, []float64{100, 100})); est != 0 {
+		t.Errorf("EstimateIntersect() = %d on a single leaf, expected 0", est)
+	}
+}
+
+func TestCountIntersect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 50; i++ {
+				bb := mustRect(Point{rnd.Float64()*120 - 10, rnd.Float64()*120 - 10}, []float64{rnd.Float64() * 60, rnd.Float64() * 60})
+				if count, expected := rt.CountIntersect(bb), len(rt.SearchIntersect(bb)); count != expected {
+					t.Errorf("CountIntersect(%v) = %d, expected %d", bb, count, expected)
+				}
+			}
+
+			all := mustRect(Point{-10, -10}, []float64{200, 200})
+			if count := rt.CountIntersect(all); count != len(things) {
+				t.Errorf("CountIntersect(%v) = %d, expected %d", all, count, len(things))
+			}
+		})
+	}
+
+	if count := NewTree(2, 3, 8).CountIntersect(mustRect(Point{0, 0}, []float64{1, 1})); count != 0 {
+		t.Errorf("CountIntersect on an empty tree = %d", count)
+	}
+}
+
+func TestSearchIntersectIter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			bb := mustRect(Point{10, 10}, []float64{60, 60})
+
+			expected := rt.SearchIntersect(bb)
+			var actual []Spatial
+			for obj := range rt.SearchIntersectIter(bb) {
+				actual = append(actual, obj)
+			}
+			if len(actual) != len(expected) {
+				t.Fatalf("SearchIntersectIter() yielded %d objects, expected %d", len(actual), len(expected))
+			}
+			for i := range actual {
+				if actual[i] != expected[i] {
+					t.Errorf("SearchIntersectIter() yielded %v at index %d, expected %v", actual[i], i, expected[i])
+				}
+			}
+
+			if limited := rt.SearchIntersectIter(bb, LimitFilter(3)); len(slices.Collect(limited)) != 3 {
+				t.Errorf("SearchIntersectIter() with LimitFilter(3) yielded %d objects", len(slices.Collect(limited)))
+			}
+
+			// breaking out of the loop stops examining objects
+			examined := 0
+			counter := func(results []Spatial, object Spatial) (bool, bool) {
+				examined++
+				return false, false
+			}
+			for obj := range rt.SearchIntersectIter(bb, counter) {
+				if obj != expected[0] {
+					t.Errorf("SearchIntersectIter() first yielded %v, expected %v", obj, expected[0])
+				}
+				break
+			}
+			if examined != 1 {
+				t.Errorf("%d objects examined after breaking on the first one", examined)
+			}
+		})
+	}
+}
+
+func TestSearchIntersectWithTestFilter(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{0, 0}, []float64{2, 1}),
+		mustRect(Point{3, 1}, []float64{1, 2}),
+		mustRect(Point{1, 2}, []float64{2, 2}),
+		mustRect(Point{8, 6}, []float64{1, 1}),
+		mustRect(Point{10, 3}, []float64{1, 2}),
+		mustRect(Point{11, 7}, []float64{1, 1}),
+		mustRect(Point{2, 6}, []float64{1, 2}),
+		mustRect(Point{3, 6}, []float64{1, 2}),
+		mustRect(Point{2, 8}, []float64{1, 2}),
+		mustRect(Point{3, 8}, []float64{1, 2}),
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			bb := mustRect(Point{2, 1.5}, []float64{10, 5.5})
+
+			// intersecting indexes are 1, 2, 6, 7, 3, 4, 5
+			// rects which we do not filter out
+			var expected []Spatial
+			for _, i := range []int{1, 6, 4} {
+				expected = append(expected, things[i])
+			}
+
+			// this test filter will only pick the objects that are in expected
+			objects := rt.SearchIntersect(bb, func(results []Spatial, object Spatial) (bool, bool) {
+				for _, exp := range expected {
+					if exp == object {
+						return false, false
+					}
+				}
+				return true, false
+			})
+
+			ensureDisorderedSubset(t, objects, expected)
+		})
+	}
+}
+
+func TestSearchIntersectNoResults(t *testing.T) {
+	things := []Spatial{
+		mustRect(Point{0, 0}, []float64{2, 1}),
+		mustRect(Point{3, 1}, []float64{1, 2}),
+		mustRect(Point{1, 2}, []float64{2, 2}),
+		mustRect(Point{8, 6}, []float64{1, 1}),
+		mustRect(Point{10, 3}, []float64{1, 2}),
+		mustRect(Point{11, 7}, []float64{1, 1}),
+		mustRect(Point{2, 6}, []float64{1, 2}),
+		mustRect(Point{3, 6}, []float64{1, 2}),
+		mustRect(Point{2, 8}, []float64{1, 2}),
+		mustRect(Point{3, 8}, []float64{1, 2}),
+	}
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			bb := mustRect(Point{99, 99}, []float64{10, 5.5})
+			q := rt.SearchIntersect(bb)
+			if len(q) != 0 {
+				t.Errorf("SearchIntersect failed to return nil slice on failing query")
+			}
+		})
+	}
+}
+
+func TestSortEntries(t *testing.T) {
+	objs := []Rect{
+		mustRect(Point{1, 1}, []float64{1, 1}),
+		mustRect(Point{2, 2}, []float64{1, 1}),
+		mustRect(Point{3, 3}, []float64{1, 1})}
+	entries := []entry{
+		{objs[2], nil, &objs[2]},
+		{objs[1], nil, &objs[1]},
+		{objs[0], nil, &objs[0]},
+	}
+	sorted, dists := sortEntries(Point{0, 0}, entries)
+	if !entryEq(sorted[0], entries[2]) || !entryEq(sorted[1], entries[1]) || !entryEq(sorted[2], entries[0]) {
+		t.Errorf("sortEntries failed")
+	}
+	if dists[0] != 2 || dists[1] != 8 || dists[2] != 18 {
+		t.Errorf("sortEntries failed to calculate proper distances")
+	}
 }
 
 func TestNearestNeighbor(t *testing.T) {
@@ -1177,6 +2561,94 @@ func TestComputeBoundingBox(t *testing.T) {
 	}
 }
 
+func TestTreeBounds(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i := 0; i < 200; i++ {
+		// scattered over several orders of magnitude
+		scale := math.Pow(10, float64(rnd.Intn(4)))
+		r := mustRect(Point{(rnd.Float64() - 0.5) * scale, (rnd.Float64() - 0.5) * scale}, []float64{rnd.Float64(), rnd.Float64()})
+		things = append(things, &r)
+	}
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			bb, ok := rt.Bounds()
+			if !ok {
+				t.Fatalf("Bounds() = %v, false on a tree of %d objects", bb, rt.Size())
+			}
+			for _, thing := range things {
+				if !bb.ContainsRect(thing.Bounds()) {
+					t.Errorf("Bounds() = %v doesn't contain %v", bb, thing.Bounds())
+				}
+			}
+			// every side of the box is reached by some object
+			for i := 0; i < rt.Dim; i++ {
+				lo, hi := false, false
+				for _, thing := range things {
+					lo = lo || thing.Bounds().p[i] == bb.p[i]
+					hi = hi || thing.Bounds().q[i] == bb.q[i]
+				}
+				if !lo || !hi {
+					t.Errorf("Bounds() = %v is larger than the objects along dimension %d", bb, i)
+				}
+			}
+
+			bb.p[0] = -1e9
+			if bb, _ := rt.Bounds(); bb.p[0] == -1e9 {
+				t.Errorf("modifying the result of Bounds changed the tree")
+			}
+		})
+	}
+
+	if bb, ok := NewTree(2, 3, 8).Bounds(); ok || len(bb.p) != 0 {
+		t.Errorf("Bounds() = %v, %v on an empty tree, expected the zero Rect and false", bb, ok)
+	}
+}
+
+func TestRootBounds(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 300)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			rects := rt.RootBounds()
+			if len(rects) != len(rt.root.entries) {
+				t.Fatalf("RootBounds() returned %d boxes, expected %d", len(rects), len(rt.root.entries))
+			}
+			if !rt.root.leaf && len(rects) < 2 {
+				t.Errorf("RootBounds() returned %d boxes for an interior root", len(rects))
+			}
+			if union, expected := boundingBoxOf(rt.GetAll()), unionOf(rects); !union.Equal(expected) {
+				t.Errorf("RootBounds() boxes union to %v, expected the bounds %v of the tree", expected, union)
+			}
+
+			// the boxes are copies
+			rects[0].p[0] = -1000
+			if rt.RootBounds()[0].p[0] == -1000 {
+				t.Errorf("modifying the result of RootBounds changed the tree")
+			}
+			verify(t, rt)
+		})
+	}
+
+	if rects := NewTree(2, 3, 8).RootBounds(); len(rects) != 0 {
+		t.Errorf("RootBounds() = %v on an empty tree", rects)
+	}
+	r := mustRect(Point{1, 2}, []float64{3, 4})
+	if rects := NewTree(2, 3, 8, r).RootBounds(); len(rects) != 1 || !rects[0].Equal(r) {
+		t.Errorf("RootBounds() = %v on a tree holding %v", rects, r)
+	}
+}
+
+// unionOf returns the smallest rectangle containing rects.
+func unionOf(rects []Rect) Rect {
+	bb := rects[0]
+	for _, r := range rects[1:] {
+		bb = bb.Union(r)
+	}
+	return bb
+}
+
 func TestGetAllBoundingBoxes(t *testing.T) {
 	rt1 := NewTree(2, 3, 3)
 	rt2 := NewTree(2, 2, 4)
@@ -1329,33 +2801,128 @@ func TestNearestNeighborsFilters(t *testing.T) {
 	}
 }
 
-func TestNearestNeighborsHalf(t *testing.T) {
-	rects := []Rect{
-		mustRect(Point{1, 1}, []float64{1, 1}),
-		mustRect(Point{-7, -7}, []float64{1, 1}),
-		mustRect(Point{1, 3}, []float64{1, 1}),
-		mustRect(Point{7, 7}, []float64{1, 1}),
-		mustRect(Point{10, 2}, []float64{1, 1}),
-		mustRect(Point{3, 3}, []float64{1, 1}),
-	}
-	things := []Spatial{}
-	for i := range rects {
-		things = append(things, &rects[i])
+func TestNearestNeighborsFilter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 300)
+	index := make(map[Spatial]int, len(things))
+	for i, thing := range things {
+		index[thing] = i
 	}
+	everyThird := func(obj Spatial) bool { return index[obj]%3 == 0 }
 
-	p := Point{0.5, 0.5}
-	sort.Sort(byMinDist{things, p})
-
-	for _, tc := range tests(2, 3, 3, things...) {
+	for _, tc := range tests(2, 3, 8, things...) {
 		t.Run(tc.name, func(t *testing.T) {
 			rt := tc.build()
-
-			objs := rt.NearestNeighbors(3, p)
-			for i := range objs {
-				if objs[i] != things[i] {
-					t.Errorf("NearestNeighbors failed at index %d: %v != %v", i, objs[i], things[i])
+			for i := 0; i < 20; i++ {
+				p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+
+				// brute force over the objects passing the filter
+				var passing []Spatial
+				for _, thing := range things {
+					if everyThird(thing) {
+						passing = append(passing, thing)
+					}
 				}
-			}
+				sort.Stable(byMinDist{passing, p})
+
+				objs := rt.NearestNeighborsFilter(5, p, everyThird)
+				if len(objs) != 5 {
+					t.Fatalf("NearestNeighborsFilter returned %d objects, expected 5", len(objs))
+				}
+				for j, obj := range objs {
+					if !everyThird(obj) {
+						t.Errorf("NearestNeighborsFilter returned object %d, which doesn't pass the filter", index[obj])
+					}
+					if d, expected := p.minDist(obj.Bounds()), p.minDist(passing[j].Bounds()); d != expected {
+						t.Errorf("result %d at squared distance %v, expected %v", j, d, expected)
+					}
+				}
+			}
+
+			if objs := rt.NearestNeighborsFilter(len(things), Point{50, 50}, everyThird); len(objs) != len(things)/3 {
+				t.Errorf("NearestNeighborsFilter returned %d objects, expected %d", len(objs), len(things)/3)
+			}
+		})
+	}
+}
+
+func TestNearestIter(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 20; i++ {
+				p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+				expected := append([]Spatial{}, things...)
+				sort.Stable(byMinDist{expected, p})
+
+				next := rt.NearestIter(p)
+				prev := 0.0
+				for j := 0; j < 50; j++ {
+					obj, ok := next()
+					if !ok {
+						t.Fatalf("NearestIter stopped after %d objects", j)
+					}
+					d := p.minDist(obj.Bounds())
+					if d < prev {
+						t.Errorf("object %d at squared distance %v, after one at %v", j, d, prev)
+					}
+					if want := p.minDist(expected[j].Bounds()); d != want {
+						t.Errorf("object %d at squared distance %v, expected %v", j, d, want)
+					}
+					prev = d
+				}
+			}
+
+			// the whole tree can be walked in distance order
+			next := rt.NearestIter(Point{50, 50})
+			seen := make(map[Spatial]bool, len(things))
+			for obj, ok := next(); ok; obj, ok = next() {
+				seen[obj] = true
+			}
+			if len(seen) != len(things) {
+				t.Errorf("NearestIter returned %d objects, expected %d", len(seen), len(things))
+			}
+			if _, ok := next(); ok {
+				t.Errorf("NearestIter returned an object after the last one")
+			}
+		})
+	}
+
+	if _, ok := NewTree(2, 3, 8).NearestIter(Point{0, 0})(); ok {
+		t.Errorf("NearestIter returned an object from an empty tree")
+	}
+}
+
+func TestNearestNeighborsHalf(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{1, 1}, []float64{1, 1}),
+		mustRect(Point{-7, -7}, []float64{1, 1}),
+		mustRect(Point{1, 3}, []float64{1, 1}),
+		mustRect(Point{7, 7}, []float64{1, 1}),
+		mustRect(Point{10, 2}, []float64{1, 1}),
+		mustRect(Point{3, 3}, []float64{1, 1}),
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	p := Point{0.5, 0.5}
+	sort.Sort(byMinDist{things, p})
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			objs := rt.NearestNeighbors(3, p)
+			for i := range objs {
+				if objs[i] != things[i] {
+					t.Errorf("NearestNeighbors failed at index %d: %v != %v", i, objs[i], things[i])
+				}
+			}
 
 			objs = rt.NearestNeighbors(len(things)+2, p)
 			if len(objs) > len(things) {
@@ -1390,3 +2957,2354 @@ func contains(obj Spatial, slice []Spatial) bool {
 
 	return false
 }
+
+func TestNearestToRect(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{1, 1}, []float64{1, 1}),
+		mustRect(Point{-7, -7}, []float64{1, 1}),
+		mustRect(Point{1, 3}, []float64{1, 1}),
+		mustRect(Point{7, 7}, []float64{1, 1}),
+		mustRect(Point{10, 2}, []float64{1, 1}),
+		mustRect(Point{3, 3}, []float64{1, 1}),
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	query := mustRect(Point{4, 0}, []float64{2, 2})
+	expected := make([]Spatial, len(things))
+	copy(expected, things)
+	sort.SliceStable(expected, func(i, j int) bool {
+		return query.DistTo(expected[i].Bounds()) < query.DistTo(expected[j].Bounds())
+	})
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			objs := rt.NearestToRect(3, query)
+			if len(objs) != 3 {
+				t.Fatalf("NearestToRect returned %d objects, expected 3", len(objs))
+			}
+			ensureOrderedSubset(t, objs, expected)
+
+			objs = rt.NearestToRect(len(things)+2, query)
+			if len(objs) != len(things) {
+				t.Fatalf("NearestToRect returned %d objects, expected %d", len(objs), len(things))
+			}
+			ensureOrderedSubset(t, objs, expected)
+		})
+	}
+}
+
+// siblingOverlap sums the pairwise overlap of sibling bounding boxes below n.
+func siblingOverlap(n *node) float64 {
+	total := 0.0
+	for i, e1 := range n.entries {
+		for _, e2 := range n.entries[i+1:] {
+			total += overlap(e1.bb, e2.bb)
+		}
+		if !n.leaf {
+			total += siblingOverlap(e1.child)
+		}
+	}
+	return total
+}
+
+func TestRotateSplitsReducesOverlap(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	rects := make([]Rect, 1000)
+	things := make([]Spatial, len(rects))
+	for i := range rects {
+		p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+		rects[i] = mustRect(p, []float64{rnd.Float64()*5 + 0.1, rnd.Float64()*5 + 0.1})
+		things[i] = &rects[i]
+	}
+
+	plain := NewTree(2, 3, 8)
+	rotated := NewTree(2, 3, 8)
+	rotated.RotateSplits = true
+	for _, thing := range things {
+		plain.Insert(thing)
+		rotated.Insert(thing)
+	}
+	verify(t, rotated)
+
+	before, after := siblingOverlap(plain.root), siblingOverlap(rotated.root)
+	t.Logf("sibling overlap without rotation: %.2f, with rotation: %.2f", before, after)
+	if after >= before {
+		t.Errorf("rotation did not reduce overlap: %v >= %v", after, before)
+	}
+
+	bb := mustRect(Point{20, 20}, []float64{30, 30})
+	expected := plain.SearchIntersect(bb)
+	actual := rotated.SearchIntersect(bb)
+	if len(actual) != len(expected) {
+		t.Fatalf("SearchIntersect returned %d objects, expected %d", len(actual), len(expected))
+	}
+	ensureDisorderedSubset(t, actual, expected)
+}
+
+func TestSearchContainingPoint(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{0, 0}, []float64{4, 4}),
+		mustRect(Point{2, 2}, []float64{4, 4}),
+		mustRect(Point{1, 1}, []float64{2, 2}), // p on its corner
+		mustRect(Point{5, 5}, []float64{1, 1}),
+		mustRect(Point{-3, 0}, []float64{2, 8}),
+	}
+	var things []Spatial
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+	things = append(things, randomRects(rand.New(rand.NewSource(1)), 50)...)
+
+	p := Point{3, 3}
+	var expected []Spatial
+	for _, thing := range things {
+		if thing.Bounds().ContainsPoint(p) {
+			expected = append(expected, thing)
+		}
+	}
+	if len(expected) < 3 {
+		t.Fatalf("expected p in several rectangles, got %v", expected)
+	}
+
+	for _, tc := range tests(2, 3, 4, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			results := rt.SearchContainingPoint(p)
+			if len(results) != len(expected) {
+				t.Errorf("SearchContainingPoint(%v) returned %d objects, expected %d", p, len(results), len(expected))
+			}
+			ensureDisorderedSubset(t, results, expected)
+
+			if results := rt.SearchContainingPoint(Point{-50, -50}); len(results) != 0 {
+				t.Errorf("SearchContainingPoint() outside every object = %v", results)
+			}
+		})
+	}
+}
+
+// randomPoints returns n points as degenerate rectangles, with coordinates in
+// [0, 100) along the given axes and zero along the others.
+func randomPoints(rnd *rand.Rand, n int, axes ...int) []Spatial {
+	things := make([]Spatial, n)
+	for i := range things {
+		p := make(Point, 2)
+		for _, axis := range axes {
+			p[axis] = rnd.Float64() * 100
+		}
+		r := p.ToRect(0)
+		things[i] = &r
+	}
+	return things
+}
+
+// xOverlap sums the pairwise overlap of sibling bounding boxes below n along
+// the first axis, which unlike siblingOverlap is positive for flat boxes.
+func xOverlap(n *node) float64 {
+	total := 0.0
+	for i, e1 := range n.entries {
+		for _, e2 := range n.entries[i+1:] {
+			total += math.Max(0, math.Min(e1.bb.q[0], e2.bb.q[0])-math.Max(e1.bb.p[0], e2.bb.p[0]))
+		}
+		if !n.leaf {
+			total += xOverlap(e1.child)
+		}
+	}
+	return total
+}
+
+func TestInsertPoints(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	// points spread over the plane have flat leaf entries only
+	things := randomPoints(rnd, 10000, 0, 1)
+	rt := NewTree(2, 3, 8)
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+	verify(t, rt)
+	packed := NewTree(2, 3, 8)
+	packed.Load(things...)
+	if overlap, bound := siblingOverlap(rt.root), 2*siblingOverlap(packed.root); overlap > bound {
+		t.Errorf("sibling overlap %v for random points, expected at most %v", overlap, bound)
+	}
+
+	// collinear points have flat bounding boxes at every level
+	things = randomPoints(rnd, 10000, 0)
+	for _, strategy := range []SplitStrategy{QuadraticSplit, LinearSplit} {
+		rt := NewTree(2, 3, 8)
+		rt.SplitStrategy = strategy
+		for _, thing := range things {
+			rt.Insert(thing)
+		}
+		verify(t, rt)
+		if overlap := xOverlap(rt.root); overlap > 1000 {
+			t.Errorf("sibling overlap %v along the line for collinear points with strategy %v", overlap, strategy)
+		}
+	}
+}
+
+func BenchmarkInsertPoints(b *testing.B) {
+	things := randomPoints(rand.New(rand.NewSource(1)), 10000, 0, 1)
+	for i := 0; i < b.N; i++ {
+		rt := NewTree(2, 3, 8)
+		for _, thing := range things {
+			rt.Insert(thing)
+		}
+	}
+}
+
+func TestEpsilon(t *testing.T) {
+	// 0.1 + 0.2 rounds to slightly more than 0.3, so the object lies just
+	// outside of queries whose edge is at 0.3
+	a, b := 0.1, 0.2
+	edge := a + b
+	obj := mustRect(Point{edge, 0}, []float64{1, 1})
+	rt := NewTree(2, 3, 8, &obj)
+
+	query := mustRect(Point{0, 0}, []float64{0.3, 1})
+	outer := mustRect(Point{0.3, -1}, []float64{2, 3})
+	inner := mustRect(Point{edge, 0}, []float64{1 - 1e-12, 1})
+	point := Point{0.3, 0.5}
+
+	for _, tt := range []struct {
+		epsilon float64
+		found   bool
+	}{
+		{0, false},
+		{1e-9, true},
+	} {
+		rt.Epsilon = tt.epsilon
+		if found := len(rt.SearchIntersect(query)) == 1; found != tt.found {
+			t.Errorf("with Epsilon %v, SearchIntersect(%v) found the object: %v, expected %v", tt.epsilon, query, found, tt.found)
+		}
+		if found := len(slices.Collect(rt.SearchIntersectIter(query))) == 1; found != tt.found {
+			t.Errorf("with Epsilon %v, SearchIntersectIter(%v) found the object: %v, expected %v", tt.epsilon, query, found, tt.found)
+		}
+		if found := len(rt.SearchContainingPoint(point)) == 1; found != tt.found {
+			t.Errorf("with Epsilon %v, SearchContainingPoint(%v) found the object: %v, expected %v", tt.epsilon, point, found, tt.found)
+		}
+		if found := len(rt.SearchContained(inner)) == 1; found != tt.found {
+			t.Errorf("with Epsilon %v, SearchContained(%v) found the object: %v, expected %v", tt.epsilon, inner, found, tt.found)
+		}
+		// the object is contained in outer regardless of Epsilon
+		if found := len(rt.SearchContained(outer)) == 1; !found {
+			t.Errorf("with Epsilon %v, SearchContained(%v) missed the object", tt.epsilon, outer)
+		}
+	}
+}
+
+func TestHalfOpen(t *testing.T) {
+	// a row of adjacent unit tiles
+	tiles := make([]Rect, 20)
+	things := make([]Spatial, len(tiles))
+	for i := range tiles {
+		tiles[i] = mustRect(Point{float64(i), 0}, []float64{1, 1})
+		things[i] = &tiles[i]
+	}
+
+	for _, tc := range tests(2, 3, 4, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			edge := Point{5, 0.5}
+			if objs := rt.SearchContainingPoint(edge); len(objs) != 2 {
+				t.Errorf("SearchContainingPoint(%v) returned %d closed tiles, expected 2", edge, len(objs))
+			}
+
+			rt.HalfOpen = true
+			if objs := rt.SearchContainingPoint(edge); len(objs) != 1 || objs[0] != things[5] {
+				t.Errorf("SearchContainingPoint(%v) = %v, expected only %v", edge, objs, things[5])
+			}
+			point := edge.ToRect(0)
+			if objs := rt.SearchIntersect(point); len(objs) != 1 || objs[0] != things[5] {
+				t.Errorf("SearchIntersect(%v) = %v, expected only %v", point, objs, things[5])
+			}
+			if count := rt.CountIntersect(point); count != 1 {
+				t.Errorf("CountIntersect(%v) = %d, expected 1", point, count)
+			}
+
+			// a query touching the tiles on their upper bound misses them
+			query := mustRect(Point{2, 1}, []float64{3, 1})
+			if rt.Intersects(query) {
+				t.Errorf("Intersects(%v) = true above the tiles", query)
+			}
+			query = mustRect(Point{2, 0}, []float64{3, 1})
+			if objs := rt.SearchIntersect(query); len(objs) != 3 {
+				t.Errorf("SearchIntersect(%v) returned %d tiles, expected 3", query, len(objs))
+			}
+			if count := rt.CountIntersect(query); count != 3 {
+				t.Errorf("CountIntersect(%v) = %d, expected 3", query, count)
+			}
+		})
+	}
+
+	// counts are consistent with searches, including for points on the
+	// upper faces of the queries
+	rnd := rand.New(rand.NewSource(1))
+	var objs []Spatial
+	for i := 0; i < 500; i++ {
+		r := mustRect(Point{float64(rnd.Intn(20)), float64(rnd.Intn(20))}, []float64{float64(rnd.Intn(2)), float64(rnd.Intn(2))})
+		objs = append(objs, &r)
+	}
+	rt := NewTree(2, 3, 8, objs...)
+	rt.HalfOpen = true
+	for i := 0; i < 200; i++ {
+		bb := mustRect(Point{float64(rnd.Intn(20)), float64(rnd.Intn(20))}, []float64{float64(rnd.Intn(10)), float64(rnd.Intn(10))})
+		if count, expected := rt.CountIntersect(bb), len(rt.SearchIntersect(bb)); count != expected {
+			t.Errorf("CountIntersect(%v) = %d, expected %d", bb, count, expected)
+		}
+		var brute int
+		for _, obj := range objs {
+			if intersectHalfOpen(obj.Bounds(), bb) {
+				brute++
+			}
+		}
+		if n := len(rt.SearchIntersect(bb)); n != brute {
+			t.Errorf("SearchIntersect(%v) returned %d objects, expected %d", bb, n, brute)
+		}
+	}
+}
+
+func TestSearchQuadrant(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{1, 1}, []float64{1, 1}),
+		mustRect(Point{-7, -7}, []float64{1, 1}),
+		mustRect(Point{1, -3}, []float64{1, 1}),
+		mustRect(Point{7, 7}, []float64{1, 1}),
+		mustRect(Point{-10, 2}, []float64{1, 1}),
+		mustRect(Point{-0.5, 3}, []float64{1, 1}),
+		mustRect(Point{0, 0}, []float64{2, 2}),
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			origin := Point{0, 0}
+
+			for _, q := range []struct {
+				signs    []int
+				expected []int
+			}{
+				{[]int{1, 1}, []int{0, 3, 6}},
+				{[]int{-1, -1}, []int{1}},
+				{[]int{1, -1}, []int{2}},
+				{[]int{0, 1}, []int{0, 3, 4, 5, 6}},
+				{[]int{-1, 0}, []int{1, 4}},
+				{[]int{0, 0}, []int{0, 1, 2, 3, 4, 5, 6}},
+			} {
+				var expected []Spatial
+				for _, i := range q.expected {
+					expected = append(expected, things[i])
+				}
+				objs := rt.SearchQuadrant(origin, q.signs)
+				if len(objs) != len(expected) {
+					t.Errorf("SearchQuadrant(%v, %v) returned %d objects, expected %d", origin, q.signs, len(objs), len(expected))
+				}
+				ensureDisorderedSubset(t, objs, expected)
+			}
+		})
+	}
+}
+
+func TestSearchQuadrantDimMismatch(t *testing.T) {
+	rt := NewTree(2, 3, 3)
+	defer func() {
+		if _, ok := recover().(DimError); !ok {
+			t.Errorf("Expected DimError on SearchQuadrant with wrong number of signs")
+		}
+	}()
+	rt.SearchQuadrant(Point{0, 0}, []int{1, 1, 1})
+}
+
+func TestQueryDimMismatch(t *testing.T) {
+	rt := NewTree(3, 3, 6)
+	for i := 0; i < 20; i++ {
+		rt.Insert(mustRect(Point{float64(i), float64(i), float64(i)}, []float64{1, 1, 1}))
+	}
+	bb := mustRect(Point{0, 0}, []float64{5, 5})
+	p := Point{1, 1}
+
+	for name, query := range map[string]func(){
+		"SearchIntersect":       func() { rt.SearchIntersect(bb) },
+		"SearchIntersectIter":   func() { rt.SearchIntersectIter(bb) },
+		"SearchIntersectBoxes":  func() { rt.SearchIntersectBoxes(bb) },
+		"Intersects":            func() { rt.Intersects(bb) },
+		"CountIntersect":        func() { rt.CountIntersect(bb) },
+		"EstimateIntersect":     func() { rt.EstimateIntersect(bb) },
+		"SearchContained":       func() { rt.SearchContained(bb) },
+		"SearchWithinRadius":    func() { rt.SearchWithinRadius(p, 1) },
+		"SearchContainingPoint": func() { rt.SearchContainingPoint(p) },
+		"SearchQuadrant":        func() { rt.SearchQuadrant(p, []int{1, 1}) },
+		"NearestNeighbor":       func() { rt.NearestNeighbor(p) },
+		"NearestNeighbors":      func() { rt.NearestNeighbors(3, p) },
+		"NearestNeighborBatch":  func() { rt.NearestNeighborBatch([]Point{p}) },
+		"NearestInRect":         func() { rt.NearestInRect(p, bb) },
+		"ApproxNearest":         func() { rt.ApproxNearest(p, 0.5) },
+		"NearestNeighborsFunc":  func() { rt.NearestNeighborsFunc(3, p, Point.minDist) },
+		"NearestToRect":         func() { rt.NearestToRect(3, bb) },
+		"KNNBounds":             func() { rt.KNNBounds(3, p) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				err, ok := recover().(DimError)
+				if !ok {
+					t.Fatalf("%s didn't panic with a DimError on a 2D query", name)
+				}
+				if err.Expected != 3 || err.Actual != 2 {
+					t.Errorf("%s panicked with %v, expected dimensions 3 and 2", name, err)
+				}
+			}()
+			query()
+		})
+	}
+}
+
+// structurallyEqual tests whether two subtrees have the same shape, bounding
+// boxes, and stored objects in the same order.
+func structurallyEqual(a, b *node) bool {
+	if a.leaf != b.leaf || a.level != b.level || len(a.entries) != len(b.entries) {
+		return false
+	}
+	for i := range a.entries {
+		ea, eb := a.entries[i], b.entries[i]
+		if !rectEq(ea.bb, eb.bb) || ea.obj != eb.obj {
+			return false
+		}
+		if !a.leaf && !structurallyEqual(ea.child, eb.child) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNewTreeCanonicalDimMismatch(t *testing.T) {
+	defer func() {
+		if _, ok := recover().(*DimError); !ok {
+			t.Errorf("Expected DimError on NewTreeCanonical with an object of the wrong dimension")
+		}
+	}()
+	objs := []Spatial{mustRect(Point{0, 0}, []float64{1, 1}), mustRect(Point{0, 0, 0}, []float64{1, 1, 1})}
+	NewTreeCanonical(2, 3, 8, objs)
+}
+
+func TestNewTreeCanonical(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 5, 8, 200} {
+		// place the objects on distinct cells of a grid, since objects
+		// with identical bounds can't be ordered canonically.
+		rects := make([]Rect, n)
+		things := make([]Spatial, n)
+		for i, cell := range rnd.Perm(400)[:n] {
+			p := Point{float64(cell % 20), float64(cell / 20)}
+			rects[i] = mustRect(p, []float64{float64(rnd.Intn(5) + 1), 1})
+			things[i] = &rects[i]
+		}
+
+		expected := NewTreeCanonical(2, 3, 8, things)
+		verify(t, expected)
+		if expected.Size() != n {
+			t.Errorf("NewTreeCanonical with %d objects has size %d", n, expected.Size())
+		}
+
+		for i := 0; i < 5; i++ {
+			shuffled := make([]Spatial, n)
+			copy(shuffled, things)
+			rnd.Shuffle(n, func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+
+			rt := NewTreeCanonical(2, 3, 8, shuffled)
+			if !structurallyEqual(expected.root, rt.root) {
+				t.Errorf("NewTreeCanonical with %d shuffled objects built a different tree", n)
+			}
+		}
+	}
+}
+
+func TestDeterministicSplits(t *testing.T) {
+	// many objects of identical sizes, some with identical bounds, make the
+	// split heuristics tie
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	for i, cell := range rnd.Perm(300) {
+		p := Point{float64(cell % 10), float64(cell % 30 / 10)}
+		things = append(things, &gobThing{i, p, Point{p[0] + 1, p[1] + 1}})
+	}
+
+	for name, build := range map[string]func() *Rtree{
+		"QuadraticSplit": func() *Rtree { return NewTree(2, 3, 6) },
+		"LinearSplit": func() *Rtree {
+			rt := NewTree(2, 3, 6)
+			rt.SplitStrategy = LinearSplit
+			return rt
+		},
+		"RotateSplits": func() *Rtree {
+			rt := NewTree(2, 3, 6)
+			rt.RotateSplits = true
+			return rt
+		},
+		"R*-tree": func() *Rtree { return NewTreeRStar(2, 3, 6) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			var expected string
+			for i := 0; i < 3; i++ {
+				rt := build()
+				for _, thing := range things {
+					rt.Insert(thing)
+				}
+				verify(t, rt)
+				if s := rt.String(); i == 0 {
+					expected = s
+				} else if s != expected {
+					t.Fatalf("inserting the same objects built different trees:\n%s\nand\n%s", expected, s)
+				}
+			}
+		})
+	}
+}
+
+func TestOnMutation(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{0, 0}, []float64{2, 1}),
+		mustRect(Point{3, 1}, []float64{1, 2}),
+		mustRect(Point{1, 2}, []float64{2, 2}),
+		mustRect(Point{8, 6}, []float64{1, 1}),
+	}
+
+	rt := NewTree(2, 2, 3)
+	var events []MutationEvent
+	rt.OnMutation(func(ev MutationEvent) {
+		events = append(events, ev)
+	})
+
+	for i := range rects {
+		rt.Insert(&rects[i])
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 4 inserts and 1 split, got %d events: %v", len(events), events)
+	}
+	for i, ev := range events[:3] {
+		if ev.Kind != InsertMutation || ev.Object != &rects[i] || !ev.Bounds[0].Equal(rects[i]) {
+			t.Errorf("unexpected event %d: %v", i, ev)
+		}
+	}
+	if split := events[3]; split.Kind != SplitMutation || split.Object != nil || len(split.Bounds) != 2 {
+		t.Errorf("expected split event, got %v", split)
+	}
+	if ev := events[4]; ev.Kind != InsertMutation || ev.Object != &rects[3] {
+		t.Errorf("expected insert event, got %v", ev)
+	}
+
+	events = nil
+	if !rt.Delete(&rects[3]) {
+		t.Fatalf("failed to delete %v", rects[3])
+	}
+	if len(events) == 0 || events[len(events)-1].Kind != DeleteMutation || events[len(events)-1].Object != &rects[3] {
+		t.Errorf("expected delete event, got %v", events)
+	}
+
+	events = nil
+	if rt.Delete(&rects[3]) || len(events) != 0 {
+		t.Errorf("expected no events for failed delete, got %v", events)
+	}
+
+	rt.OnMutation(nil)
+	rt.Insert(&rects[3])
+	if len(events) != 0 {
+		t.Errorf("expected no events after removing hook, got %v", events)
+	}
+}
+
+func randomRects(rnd *rand.Rand, n int) []Spatial {
+	rects := make([]Rect, n)
+	things := make([]Spatial, n)
+	for i := range rects {
+		p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+		rects[i] = mustRect(p, []float64{rnd.Float64() + 0.1, rnd.Float64() + 0.1})
+		things[i] = &rects[i]
+	}
+	return things
+}
+
+func TestLazyBounds(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 2000)
+
+	eager := NewTree(2, 3, 8)
+	lazy := NewTree(2, 3, 8)
+	lazy.LazyBounds = true
+	for _, thing := range things {
+		eager.Insert(thing)
+		lazy.Insert(thing)
+	}
+	for _, thing := range things[:500] {
+		eager.Delete(thing)
+		lazy.Delete(thing)
+	}
+	verify(t, lazy)
+
+	for i := 0; i < 20; i++ {
+		bb := mustRect(Point{rnd.Float64() * 80, rnd.Float64() * 80}, []float64{20, 20})
+		expected, actual := eager.SearchIntersect(bb), lazy.SearchIntersect(bb)
+		if len(actual) != len(expected) {
+			t.Fatalf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(actual), len(expected))
+		}
+		ensureDisorderedSubset(t, actual, expected)
+
+		p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+		expected, actual = eager.NearestNeighbors(5, p), lazy.NearestNeighbors(5, p)
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Fatalf("NearestNeighbors(5, %v) = %v, expected %v", p, actual, expected)
+			}
+		}
+	}
+
+	expectedBoxes, actualBoxes := eager.GetAllBoundingBoxes(), lazy.GetAllBoundingBoxes()
+	if len(actualBoxes) != len(expectedBoxes) {
+		t.Fatalf("GetAllBoundingBoxes returned %d boxes, expected %d", len(actualBoxes), len(expectedBoxes))
+	}
+	for i := range expectedBoxes {
+		if !actualBoxes[i].Equal(expectedBoxes[i]) {
+			t.Errorf("bounding box %d is %v, expected %v", i, actualBoxes[i], expectedBoxes[i])
+		}
+	}
+}
+
+func benchmarkLazyBounds(b *testing.B, lazy bool) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 20000)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	// a small branching factor maximizes the number of interior entries
+	rt := NewTree(2, 2, 4)
+	rt.LazyBounds = lazy
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	bb := mustRect(Point{40, 40}, []float64{5, 5})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rt.SearchIntersect(bb)
+	}
+	b.ReportMetric(float64(after.HeapAlloc)-float64(before.HeapAlloc), "tree-bytes")
+}
+
+func BenchmarkSearchIntersectEagerBounds(b *testing.B) {
+	benchmarkLazyBounds(b, false)
+}
+
+func BenchmarkSearchIntersectLazyBounds(b *testing.B) {
+	benchmarkLazyBounds(b, true)
+}
+
+func TestKNNBounds(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{1, 1}, []float64{1, 1}),
+		mustRect(Point{-7, -7}, []float64{1, 1}),
+		mustRect(Point{1, 3}, []float64{1, 1}),
+		mustRect(Point{7, 7}, []float64{1, 1}),
+		mustRect(Point{10, 2}, []float64{1, 1}),
+		mustRect(Point{3, 3}, []float64{1, 1}),
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	for _, tc := range tests(2, 3, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			p := Point{0.5, 0.5}
+			bb, objs := rt.KNNBounds(3, p)
+			expected := rt.NearestNeighbors(3, p)
+			ensureOrderedSubset(t, objs, expected)
+			if len(objs) != len(expected) {
+				t.Fatalf("KNNBounds returned %d objects, expected %d", len(objs), len(expected))
+			}
+
+			// objects 0, 2 and 5 span [1, 4]x[1, 4]
+			if exp := mustRect(Point{1, 1}, []float64{3, 3}); !bb.Equal(exp) {
+				t.Errorf("KNNBounds returned bounds %v, expected %v", bb, exp)
+			}
+		})
+	}
+
+	bb, objs := NewTree(2, 3, 3).KNNBounds(3, Point{0, 0})
+	if len(objs) != 0 || len(bb.p) != 0 {
+		t.Errorf("KNNBounds on empty tree returned %v, %v", bb, objs)
+	}
+}
+
+func TestLinearScanThreshold(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 40)
+
+	structured := NewTree(2, 2, 4)
+	flat := NewTree(2, 2, 4)
+	flat.LinearScanThreshold = 32
+	for i, thing := range things {
+		structured.Insert(thing)
+		flat.Insert(thing)
+
+		if flat.Size() != i+1 {
+			t.Fatalf("Size() = %d after %d inserts", flat.Size(), i+1)
+		}
+		if i < 32 && !flat.root.leaf {
+			t.Fatalf("tree with %d objects isn't flat", i+1)
+		}
+		if i >= 32 {
+			verify(t, flat)
+		}
+
+		bb := mustRect(Point{rnd.Float64() * 60, rnd.Float64() * 60}, []float64{40, 40})
+		expected, actual := structured.SearchIntersect(bb), flat.SearchIntersect(bb)
+		if len(actual) != len(expected) {
+			t.Fatalf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(actual), len(expected))
+		}
+		ensureDisorderedSubset(t, actual, expected)
+
+		p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+		expected, actual = structured.NearestNeighbors(3, p), flat.NearestNeighbors(3, p)
+		for j := range expected {
+			if actual[j] != expected[j] {
+				t.Fatalf("NearestNeighbors(3, %v) = %v, expected %v", p, actual, expected)
+			}
+		}
+	}
+
+	for _, thing := range things[:20] {
+		if !flat.Delete(thing) {
+			t.Errorf("failed to delete %v", thing)
+		}
+	}
+	verify(t, flat)
+}
+
+func BenchmarkSmallTrees(b *testing.B) {
+	for _, size := range []int{1, 4, 8, 16, 32} {
+		for _, threshold := range []int{0, 32} {
+			rt := NewTree(2, 2, 4)
+			rt.LinearScanThreshold = threshold
+			for _, thing := range randomRects(rand.New(rand.NewSource(1)), size) {
+				rt.Insert(thing)
+			}
+			bb := mustRect(Point{25, 25}, []float64{50, 50})
+			p := Point{50, 50}
+
+			b.Run(fmt.Sprintf("SearchIntersect/size=%d/threshold=%d", size, threshold), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					rt.SearchIntersect(bb)
+				}
+			})
+			b.Run(fmt.Sprintf("NearestNeighbors/size=%d/threshold=%d", size, threshold), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					rt.NearestNeighbors(3, p)
+				}
+			})
+		}
+	}
+}
+
+func TestNearestNeighborRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 100; i++ {
+				p := Point{rnd.Float64()*120 - 10, rnd.Float64()*120 - 10}
+
+				// brute force
+				best := math.MaxFloat64
+				for _, thing := range things {
+					if d := p.minDist(thing.Bounds()); d < best {
+						best = d
+					}
+				}
+
+				obj := rt.NearestNeighbor(p)
+				if d := p.minDist(obj.Bounds()); d != best {
+					t.Errorf("NearestNeighbor(%v) = %v at squared distance %v, expected %v", p, obj, d, best)
+				}
+			}
+		})
+	}
+
+	if obj := NewTree(2, 3, 8).NearestNeighbor(Point{0, 0}); obj != nil {
+		t.Errorf("NearestNeighbor on empty tree returned %v", obj)
+	}
+}
+
+func TestNearestNeighborMinMaxDistPruning(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	rt := NewTree(2, 3, 8)
+	for _, thing := range randomRects(rnd, 5000) {
+		rt.Insert(thing)
+	}
+
+	total, baseline := 0, 0
+	for i := 0; i < 200; i++ {
+		p := Point{rnd.Float64()*120 - 10, rnd.Float64()*120 - 10}
+		visited := 0
+		_, d := rt.nearestNeighborVisits(p, &visited)
+		baselineVisited := 0
+		_, baselineD := minDistNearest(p, rt.root, math.MaxFloat64, &baselineVisited)
+		if d != baselineD {
+			t.Errorf("nearest neighbor of %v at squared distance %v, expected %v", p, d, baselineD)
+		}
+		if visited > baselineVisited {
+			t.Errorf("nearest neighbor of %v visited %d nodes, %d without MINMAXDIST", p, visited, baselineVisited)
+		}
+		total += visited
+		baseline += baselineVisited
+	}
+	if total >= baseline {
+		t.Errorf("nearest neighbor queries visited %d nodes, %d without MINMAXDIST", total, baseline)
+	}
+}
+
+// minDistNearest is a nearest neighbor search visiting the branches by
+// MINDIST and pruning them only by the distance to the best object found so
+// far, counting the nodes visited in *visited.
+func minDistNearest(p Point, n *node, d float64, visited *int) (Spatial, float64) {
+	*visited++
+	var nearest Spatial
+	if n.leaf {
+		for _, e := range n.entries {
+			if dist := p.minDist(e.bb); dist < d {
+				d, nearest = dist, e.obj
+			}
+		}
+		return nearest, d
+	}
+	branches, dists := sortEntries(p, n.entries)
+	for i, e := range branches {
+		if dists[i] > d {
+			break
+		}
+		if obj, dist := minDistNearest(p, e.child, d, visited); obj != nil {
+			d, nearest = dist, obj
+		}
+	}
+	return nearest, d
+}
+
+func BenchmarkNearestNeighbor(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	rt := NewTree(2, 3, 8)
+	for _, thing := range randomRects(rnd, 10000) {
+		rt.Insert(thing)
+	}
+	points := make([]Point, 1000)
+	for i := range points {
+		points[i] = Point{rnd.Float64() * 100, rnd.Float64() * 100}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rt.NearestNeighbor(points[i%len(points)])
+	}
+}
+
+func BenchmarkNearestNeighborBatch(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	rt := NewTree(2, 3, 8)
+	for _, thing := range randomRects(rnd, 10000) {
+		rt.Insert(thing)
+	}
+	points := make([]Point, 1000)
+	for i := range points {
+		points[i] = Point{rnd.Float64() * 100, rnd.Float64() * 100}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rt.NearestNeighborBatch(points)
+	}
+}
+
+func TestApproxNearest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for _, epsilon := range []float64{0, 0.1, 0.5, 2} {
+				for i := 0; i < 50; i++ {
+					p := Point{rnd.Float64()*140 - 20, rnd.Float64()*140 - 20}
+					nearest := math.Inf(1)
+					for _, thing := range things {
+						nearest = math.Min(nearest, math.Sqrt(thing.Bounds().MinDist(p)))
+					}
+					obj := rt.ApproxNearest(p, epsilon)
+					if obj == nil {
+						t.Fatalf("ApproxNearest(%v, %v) = nil", p, epsilon)
+					}
+					if d := math.Sqrt(obj.Bounds().MinDist(p)); d > (1+epsilon)*nearest+EPS {
+						t.Errorf("ApproxNearest(%v, %v) returned an object at distance %v, expected at most (1+epsilon)*%v", p, epsilon, d, nearest)
+					}
+				}
+			}
+		})
+	}
+
+	if obj := NewTree(2, 3, 8).ApproxNearest(Point{0, 0}, 0.5); obj != nil {
+		t.Errorf("ApproxNearest on an empty tree = %v, expected nil", obj)
+	}
+	defer func() {
+		if _, ok := recover().(DistError); !ok {
+			t.Errorf("ApproxNearest didn't panic with a DistError on a negative epsilon")
+		}
+	}()
+	NewTree(2, 3, 8).ApproxNearest(Point{0, 0}, -1)
+}
+
+func TestClosestPair(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []int{2, 3, 10, 50, 300} {
+		// points rarely overlap, unlike the rectangles of randomRects
+		points := make([]Rect, n)
+		things := make([]Spatial, n)
+		for i := range points {
+			points[i] = Point{rnd.Float64() * 100, rnd.Float64() * 100}.ToRect(0)
+			things[i] = &points[i]
+		}
+		for _, objs := range [][]Spatial{things, randomRects(rnd, n)} {
+			expected := math.Inf(1)
+			for i, a := range objs {
+				for _, b := range objs[i+1:] {
+					expected = math.Min(expected, a.Bounds().DistTo(b.Bounds()))
+				}
+			}
+
+			for _, tc := range tests(2, 3, 8, objs...) {
+				rt := tc.build()
+				a, b, d := rt.ClosestPair()
+				if a == nil || b == nil || a == b {
+					t.Fatalf("%s: ClosestPair() of %d objects = %v, %v", tc.name, n, a, b)
+				}
+				if d != expected {
+					t.Errorf("%s: ClosestPair() of %d objects has distance %v, expected %v", tc.name, n, d, expected)
+				}
+				if actual := a.Bounds().DistTo(b.Bounds()); actual != d {
+					t.Errorf("%s: ClosestPair() returned objects at distance %v, not %v", tc.name, actual, d)
+				}
+			}
+		}
+	}
+
+	for _, objs := range [][]Spatial{nil, randomRects(rnd, 1)} {
+		if a, b, d := NewTree(2, 3, 8, objs...).ClosestPair(); a != nil || b != nil || d != 0 {
+			t.Errorf("ClosestPair() of %d objects = %v, %v, %v, expected nil, nil, 0", len(objs), a, b, d)
+		}
+	}
+}
+
+func TestNearestInRect(t *testing.T) {
+	near := mustRect(Point{1, 1}, []float64{1, 1})
+	far := mustRect(Point{5, 5}, []float64{1, 1})
+	farther := mustRect(Point{8, 8}, []float64{1, 1})
+	rt := NewTree(2, 2, 4, &near, &far, &farther)
+	for i := 0; i < 50; i++ {
+		r := mustRect(Point{20 + float64(i), 20}, []float64{1, 1})
+		rt.Insert(&r)
+	}
+
+	p := Point{0, 0}
+	if obj := rt.NearestNeighbor(p); obj != &near {
+		t.Fatalf("NearestNeighbor(%v) = %v, expected %v", p, obj, &near)
+	}
+	viewport := mustRect(Point{4, 4}, []float64{6, 6})
+	if obj := rt.NearestInRect(p, viewport); obj != &far {
+		t.Errorf("NearestInRect(%v, %v) = %v, expected %v", p, viewport, obj, &far)
+	}
+	empty := mustRect(Point{-10, -10}, []float64{1, 1})
+	if obj := rt.NearestInRect(p, empty); obj != nil {
+		t.Errorf("NearestInRect(%v, %v) = %v, expected nil", p, empty, obj)
+	}
+	if obj := NewTree(2, 2, 4).NearestInRect(p, viewport); obj != nil {
+		t.Errorf("NearestInRect on an empty tree = %v, expected nil", obj)
+	}
+}
+
+func TestNearestInRectRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for i := 0; i < 50; i++ {
+				p := Point{rnd.Float64() * 100, rnd.Float64() * 100}
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{15, 15})
+				var expected Spatial
+				for _, obj := range rt.SearchIntersect(bb) {
+					if expected == nil || obj.Bounds().MinDist(p) < expected.Bounds().MinDist(p) {
+						expected = obj
+					}
+				}
+				obj := rt.NearestInRect(p, bb)
+				if expected == nil {
+					if obj != nil {
+						t.Errorf("NearestInRect(%v, %v) = %v, expected nil", p, bb, obj)
+					}
+					continue
+				}
+				if obj == nil || obj.Bounds().MinDist(p) != expected.Bounds().MinDist(p) {
+					t.Errorf("NearestInRect(%v, %v) = %v, expected %v", p, bb, obj, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestNearestNeighborBatch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	points := make([]Point, 500)
+	for i := range points {
+		points[i] = Point{rnd.Float64()*120 - 10, rnd.Float64()*120 - 10}
+	}
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			objs := rt.NearestNeighborBatch(points)
+			if len(objs) != len(points) {
+				t.Fatalf("NearestNeighborBatch returned %d objects for %d points", len(objs), len(points))
+			}
+			for i, p := range points {
+				if expected := rt.NearestNeighbor(p); objs[i] != expected {
+					t.Errorf("NearestNeighborBatch returned %v for %v, expected %v", objs[i], p, expected)
+				}
+			}
+		})
+	}
+
+	if objs := NewTree(2, 3, 8).NearestNeighborBatch(points[:3]); len(objs) != 3 || objs[0] != nil {
+		t.Errorf("NearestNeighborBatch() = %v on an empty tree", objs)
+	}
+}
+
+func TestNearestNeighborsRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(2))
+	things := randomRects(rnd, 1000)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			for _, k := range []int{1, 5, 50, len(things) + 10} {
+				p := Point{rnd.Float64()*120 - 10, rnd.Float64()*120 - 10}
+
+				// brute force
+				expected := append([]Spatial(nil), things...)
+				sort.Sort(byMinDist{expected, p})
+				if k < len(expected) {
+					expected = expected[:k]
+				}
+
+				objs := rt.NearestNeighbors(k, p)
+				if len(objs) != len(expected) {
+					t.Fatalf("NearestNeighbors(%d, %v) returned %d objects, expected %d", k, p, len(objs), len(expected))
+				}
+				for i := range objs {
+					if got, want := p.minDist(objs[i].Bounds()), p.minDist(expected[i].Bounds()); got != want {
+						t.Errorf("NearestNeighbors(%d, %v) at index %d: squared distance %v, expected %v", k, p, i, got, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSearchIntersectClosed(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		rects    []Rect
+		query    Rect
+		expected []int
+	}{
+		{
+			name: "2D",
+			rects: []Rect{
+				mustRect(Point{0, 0}, []float64{10, 10}), // contains the query
+				mustRect(Point{2, 2}, []float64{1, 1}),   // inside the query
+				mustRect(Point{4, 1}, []float64{2, 2}),   // touches the right edge
+				mustRect(Point{4, 4}, []float64{1, 1}),   // touches a corner
+				mustRect(Point{4.5, 1}, []float64{1, 1}), // disjoint
+				mustRect(Point{-5, -5}, []float64{1, 1}), // disjoint
+			},
+			query:    mustRect(Point{1, 1}, []float64{3, 3}),
+			expected: []int{0, 1, 2, 3},
+		},
+		{
+			name: "3D",
+			rects: []Rect{
+				mustRect(Point{0, 0, 0}, []float64{10, 10, 10}), // contains the query
+				mustRect(Point{2, 2, 2}, []float64{1, 1, 1}),    // inside the query
+				mustRect(Point{1, 1, 4}, []float64{1, 1, 1}),    // touches the top face
+				mustRect(Point{4, 4, 4}, []float64{1, 1, 1}),    // touches a corner
+				mustRect(Point{1, 1, 4.5}, []float64{1, 1, 1}),  // disjoint
+				mustRect(Point{20, 0, 0}, []float64{1, 1, 1}),   // disjoint
+			},
+			query:    mustRect(Point{1, 1, 1}, []float64{3, 3, 3}),
+			expected: []int{0, 1, 2, 3},
+		},
+	} {
+		things := []Spatial{}
+		for i := range tt.rects {
+			things = append(things, &tt.rects[i])
+		}
+		var expected []Spatial
+		for _, i := range tt.expected {
+			expected = append(expected, things[i])
+		}
+
+		for _, tc := range tests(len(tt.query.p), 2, 3, things...) {
+			t.Run(tt.name+"/"+tc.name, func(t *testing.T) {
+				rt := tc.build()
+
+				q := rt.SearchIntersect(tt.query)
+				ensureDisorderedSubset(t, q, expected)
+				if len(q) != len(expected) {
+					t.Errorf("SearchIntersect(%v) returned %d objects, expected %d", tt.query, len(q), len(expected))
+				}
+
+				dim := len(tt.query.p)
+				far := mustRect(Point{100, 100, 100}[:dim], []float64{1, 1, 1}[:dim])
+				if q := rt.SearchIntersect(far); q == nil || len(q) != 0 {
+					t.Errorf("SearchIntersect(%v) = %v, expected an empty non-nil slice", far, q)
+				}
+			})
+		}
+	}
+}
+
+func TestSearchContained(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{1, 1}, []float64{3, 3}),     // coincides with the query
+		mustRect(Point{2, 2}, []float64{1, 1}),     // strictly inside
+		mustRect(Point{1, 2}, []float64{1, 2}),     // shares the left edge
+		mustRect(Point{3, 3}, []float64{1.5, 0.5}), // sticks out on the right
+		mustRect(Point{0, 0}, []float64{10, 10}),   // contains the query
+		mustRect(Point{5, 5}, []float64{1, 1}),     // disjoint
+	}
+	things := []Spatial{}
+	for i := range rects {
+		things = append(things, &rects[i])
+	}
+
+	for _, tc := range tests(2, 2, 3, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			bb := mustRect(Point{1, 1}, []float64{3, 3})
+			q := rt.SearchContained(bb)
+
+			expected := []Spatial{things[0], things[1], things[2]}
+			ensureDisorderedSubset(t, q, expected)
+			if len(q) != len(expected) {
+				t.Errorf("SearchContained(%v) returned %d objects, expected %d", bb, len(q), len(expected))
+			}
+
+			if q := rt.SearchContained(bb, LimitFilter(1)); len(q) != 1 {
+				t.Errorf("SearchContained(%v) with a limit of 1 returned %d objects", bb, len(q))
+			}
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	rects := []Rect{
+		mustRect(Point{0, 0}, []float64{1, 1}),
+		mustRect(Point{2, 2}, []float64{1, 1}),
+		mustRect(Point{4, 0}, []float64{1, 1}),
+		mustRect(Point{6, 2}, []float64{1, 1}),
+	}
+	rt := NewTree(2, 2, 3)
+	for i := range rects {
+		rt.Insert(&rects[i])
+	}
+
+	s := rt.String()
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	header := "Rtree{Dim: 2, MinChildren: 2, MaxChildren: 3, Size: 4, Depth: 2}"
+	if lines[0] != header {
+		t.Errorf("String() header = %q, expected %q", lines[0], header)
+	}
+	if !strings.HasPrefix(lines[1], "  interior node, level 2, 2 entries") {
+		t.Errorf("String() root line = %q", lines[1])
+	}
+
+	// one line for the root, one per interior entry and child, and one per object
+	if len(lines) != 1+1+2*2+len(rects) {
+		t.Errorf("String() has %d lines, expected %d:\n%s", len(lines), 1+1+2*2+len(rects), s)
+	}
+	for i := range rects {
+		if !strings.Contains(s, rects[i].String()) {
+			t.Errorf("String() doesn't mention %v:\n%s", rects[i], s)
+		}
+	}
+	if s != rt.String() {
+		t.Errorf("String() isn't deterministic")
+	}
+}
+
+// underfull returns the level of a non-root node with fewer than min entries
+// below n, or 0 if there is none.
+func underfull(n *node, min int) int {
+	if n.parent != nil && len(n.entries) < min {
+		return n.level
+	}
+	if n.leaf {
+		return 0
+	}
+	for _, e := range n.entries {
+		if level := underfull(e.child, min); level != 0 {
+			return level
+		}
+	}
+	return 0
+}
+
+func TestLoad(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, n := range []int{1, 5, 8, 9, 100, 1000} {
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			things := randomRects(rnd, n)
+			rt := NewTree(2, 3, 8)
+			rt.Load(things[:n/2]...)
+			rt.Load(things[n/2:]...)
+
+			verify(t, rt)
+			if rt.Size() != n {
+				t.Errorf("Size() = %d, expected %d", rt.Size(), n)
+			}
+			if level := underfull(rt.root, rt.MinChildren); level != 0 {
+				t.Errorf("underfull node at level %d", level)
+			}
+
+			all := mustRect(Point{-1, -1}, []float64{200, 200})
+			q := rt.SearchIntersect(all)
+			ensureDisorderedSubset(t, q, things)
+			if len(q) != n {
+				t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), n)
+			}
+
+			// the tree remains usable by Insert and Delete
+			extra := randomRects(rnd, 50)
+			for _, thing := range extra {
+				rt.Insert(thing)
+			}
+			for _, thing := range things {
+				if !rt.Delete(thing) {
+					t.Fatalf("failed to delete %v", thing)
+				}
+			}
+			verify(t, rt)
+			if rt.Size() != len(extra) {
+				t.Errorf("Size() = %d, expected %d", rt.Size(), len(extra))
+			}
+		})
+	}
+}
+
+func TestLoadStream(t *testing.T) {
+	const n = 100000
+	rnd := rand.New(rand.NewSource(1))
+	var things []Spatial
+	next := func() (Spatial, bool) {
+		if len(things) == n {
+			return nil, false
+		}
+		thing := Point{rnd.Float64() * 1000, rnd.Float64() * 1000}.ToRect(0.5)
+		things = append(things, &thing)
+		return &thing, true
+	}
+
+	rt, err := LoadStream(2, 5, 16, next)
+	if err != nil {
+		t.Fatalf("LoadStream() = %v", err)
+	}
+	if err := rt.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if rt.Size() != n {
+		t.Errorf("Size() = %d, expected %d", rt.Size(), n)
+	}
+	for i := 0; i < 20; i++ {
+		bb := mustRect(Point{rnd.Float64() * 1000, rnd.Float64() * 1000}, []float64{20, 20})
+		var expected []Spatial
+		for _, thing := range things {
+			if intersect(thing.Bounds(), bb) {
+				expected = append(expected, thing)
+			}
+		}
+		q := rt.SearchIntersect(bb)
+		ensureDisorderedSubset(t, expected, q)
+		if len(q) != len(expected) {
+			t.Errorf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(q), len(expected))
+		}
+	}
+
+	// the leaves share the array of entries, which inserts must not overwrite
+	for _, thing := range randomRects(rnd, 1000) {
+		rt.Insert(thing)
+	}
+	for _, thing := range things[:1000] {
+		if !rt.Delete(thing) {
+			t.Fatalf("failed to delete %v", thing)
+		}
+	}
+	if err := rt.Validate(); err != nil {
+		t.Errorf("Validate() = %v after inserts and deletes", err)
+	}
+
+	empty, err := LoadStream(2, 5, 16, func() (Spatial, bool) { return nil, false })
+	if err != nil || empty.Size() != 0 {
+		t.Errorf("LoadStream() = %v, %v on an empty stream", empty, err)
+	}
+	verify(t, empty)
+
+	if _, err := LoadStream(2, 5, 8, next); err == nil {
+		t.Errorf("LoadStream() = nil with invalid parameters")
+	}
+	p3 := mustRect(Point{0, 0, 0}, []float64{1, 1, 1})
+	var dimErr *DimError
+	if _, err := LoadStream(2, 5, 16, func() (Spatial, bool) { return &p3, true }); !errors.As(err, &dimErr) {
+		t.Errorf("LoadStream() = %v with a 3D object, expected a *DimError", err)
+	}
+}
+
+func TestLoad3D(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	rects := make([]Rect, 500)
+	things := make([]Spatial, len(rects))
+	for i := range rects {
+		p := Point{rnd.Float64() * 100, rnd.Float64() * 100, rnd.Float64() * 100}
+		rects[i] = mustRect(p, []float64{1, 1, 1})
+		things[i] = &rects[i]
+	}
+
+	rt := NewTree(3, 3, 6)
+	rt.Load(things...)
+	verify(t, rt)
+	if level := underfull(rt.root, rt.MinChildren); level != 0 {
+		t.Errorf("underfull node at level %d", level)
+	}
+
+	bb := mustRect(Point{20, 20, 20}, []float64{30, 30, 30})
+	var expected []Spatial
+	for _, thing := range things {
+		if intersect(thing.Bounds(), bb) {
+			expected = append(expected, thing)
+		}
+	}
+	q := rt.SearchIntersect(bb)
+	ensureDisorderedSubset(t, q, expected)
+	if len(q) != len(expected) {
+		t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(expected))
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	rt := NewTree(2, 3, 8)
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+	// deleting most objects leaves many sparse nodes behind
+	for _, thing := range things[:800] {
+		rt.Delete(thing)
+	}
+	before := rt.Stats()
+
+	bb := mustRect(Point{20, 20}, []float64{40, 40})
+	expected := rt.SearchIntersect(bb)
+	rt.Rebuild()
+
+	verify(t, rt)
+	if rt.Size() != 200 {
+		t.Errorf("Size() = %d after Rebuild, expected 200", rt.Size())
+	}
+	if rt.Dim != 2 || rt.MinChildren != 3 || rt.MaxChildren != 8 {
+		t.Errorf("Rebuild changed the parameters to %d, %d, %d", rt.Dim, rt.MinChildren, rt.MaxChildren)
+	}
+	after := rt.Stats()
+	if after.Levels[0].FillFactor <= before.Levels[0].FillFactor {
+		t.Errorf("leaf fill factor %v after Rebuild, %v before", after.Levels[0].FillFactor, before.Levels[0].FillFactor)
+	}
+	if after.Levels[0].Nodes >= before.Levels[0].Nodes {
+		t.Errorf("%d leaves after Rebuild, %d before", after.Levels[0].Nodes, before.Levels[0].Nodes)
+	}
+
+	actual := rt.SearchIntersect(bb)
+	ensureDisorderedSubset(t, actual, expected)
+	if len(actual) != len(expected) {
+		t.Errorf("SearchIntersect returned %d objects after Rebuild, expected %d", len(actual), len(expected))
+	}
+
+	// the tree remains usable by Insert and Delete
+	for _, thing := range things[:100] {
+		rt.Insert(thing)
+	}
+	for _, thing := range things[800:] {
+		if !rt.Delete(thing) {
+			t.Fatalf("failed to delete %v after Rebuild", thing)
+		}
+	}
+	verify(t, rt)
+
+	empty := NewTree(2, 3, 8)
+	empty.Rebuild()
+	if empty.Size() != 0 || empty.Depth() != 1 {
+		t.Errorf("Rebuild of an empty tree has size %d and depth %d", empty.Size(), empty.Depth())
+	}
+}
+
+// countingThing counts the calls to its Bounds method.
+type countingThing struct {
+	bb    Rect
+	calls int
+}
+
+func (t *countingThing) Bounds() Rect {
+	t.calls++
+	return t.bb
+}
+
+func TestLoadEvents(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 100)
+	counted := make([]Spatial, len(things))
+	for i, thing := range things {
+		counted[i] = &countingThing{bb: thing.Bounds()}
+	}
+
+	rt := NewTree(2, 3, 8)
+	var events []MutationEvent
+	rt.OnMutation(func(ev MutationEvent) {
+		events = append(events, ev)
+	})
+	rt.Load(counted...)
+
+	if len(events) != len(counted) {
+		t.Fatalf("Load() reported %d events, expected %d", len(events), len(counted))
+	}
+	for i, ev := range events {
+		thing := counted[i].(*countingThing)
+		if ev.Kind != InsertMutation || ev.Object != thing || !ev.Bounds[0].Equal(thing.bb) {
+			t.Errorf("event %d = %v, expected the insertion of %v", i, ev, thing.bb)
+		}
+		if thing.calls != 1 {
+			t.Errorf("Load() called Bounds %d times on %v, expected once", thing.calls, thing.bb)
+		}
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	things := randomRects(rand.New(rand.NewSource(1)), 10000)
+	b.Run("Load", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewTree(2, 4, 16).Load(things...)
+		}
+	})
+	b.Run("Insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			rt := NewTree(2, 4, 16)
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+		}
+	})
+}
+
+func TestInsertBatch(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 300)
+	batch := randomRects(rnd, 500)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			sequential := tc.build()
+			for _, thing := range batch {
+				sequential.Insert(thing)
+			}
+
+			if err := rt.InsertBatch(batch); err != nil {
+				t.Fatalf("InsertBatch() = %v", err)
+			}
+			verify(t, rt)
+			if rt.Size() != len(things)+len(batch) {
+				t.Errorf("Size() = %d after InsertBatch, expected %d", rt.Size(), len(things)+len(batch))
+			}
+			if n := len(rt.GetAll()); n != rt.Size() {
+				t.Errorf("GetAll() returned %d objects, expected %d", n, rt.Size())
+			}
+
+			for i := 0; i < 50; i++ {
+				bb := Point{rnd.Float64() * 100, rnd.Float64() * 100}.ToRect(rnd.Float64() * 20)
+				expected := sequential.SearchIntersect(bb)
+				actual := rt.SearchIntersect(bb)
+				if len(actual) != len(expected) {
+					t.Errorf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(actual), len(expected))
+				}
+				ensureDisorderedSubset(t, actual, expected)
+			}
+		})
+	}
+}
+
+func TestInsertBatchDimError(t *testing.T) {
+	rt := NewTree(2, 3, 8, randomRects(rand.New(rand.NewSource(1)), 100)...)
+	batch := []Spatial{
+		mustRect(Point{0, 0}, []float64{1, 1}),
+		mustRect(Point{0, 0, 0}, []float64{1, 1, 1}),
+	}
+	if _, ok := rt.InsertBatch(batch).(*DimError); !ok {
+		t.Errorf("InsertBatch() didn't return a *DimError")
+	}
+	if rt.Size() != 100 {
+		t.Errorf("Size() = %d after a failed InsertBatch, expected 100", rt.Size())
+	}
+}
+
+func BenchmarkInsertBatch(b *testing.B) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 10000)
+	batch := randomRects(rnd, 10000)
+	b.Run("InsertBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			rt := NewTree(2, 4, 16, things...)
+			b.StartTimer()
+			rt.InsertBatch(batch)
+		}
+	})
+	b.Run("Insert", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			rt := NewTree(2, 4, 16, things...)
+			b.StartTimer()
+			for _, thing := range batch {
+				rt.Insert(thing)
+			}
+		}
+	})
+}
+
+// leafFill returns the average number of entries per leaf below n.
+func leafFill(n *node) float64 {
+	var leaves, entries int
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n.leaf {
+			leaves++
+			entries += len(n.entries)
+			return
+		}
+		for _, e := range n.entries {
+			walk(e.child)
+		}
+	}
+	walk(n)
+	return float64(entries) / float64(leaves)
+}
+
+func TestNewTreeRStar(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 2000)
+
+	plain := NewTree(2, 3, 10)
+	rstar := NewTreeRStar(2, 3, 10)
+	for _, thing := range things {
+		plain.Insert(thing)
+		rstar.Insert(thing)
+	}
+	verify(t, rstar)
+	if rstar.Size() != len(things) {
+		t.Errorf("Size() = %d, expected %d", rstar.Size(), len(things))
+	}
+	if rstar.reinsertedLevels != nil {
+		t.Errorf("reinsertion levels leaked out of Insert")
+	}
+
+	plainFill, rstarFill := leafFill(plain.root), leafFill(rstar.root)
+	if rstarFill <= plainFill {
+		t.Errorf("R*-tree leaves hold %.2f entries on average, no more than %.2f with plain splits", rstarFill, plainFill)
+	}
+
+	all := mustRect(Point{-1, -1}, []float64{200, 200})
+	q := rstar.SearchIntersect(all)
+	ensureDisorderedSubset(t, q, things)
+	if len(q) != len(things) {
+		t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(things))
+	}
+
+	for _, thing := range things[:1000] {
+		if !rstar.Delete(thing) {
+			t.Fatalf("failed to delete %v", thing)
+		}
+	}
+	verify(t, rstar)
+}
+
+func TestNewTreeRStarSortedInserts(t *testing.T) {
+	// inserting sorted data makes the same nodes overflow repeatedly
+	rt := NewTreeRStar(1, 2, 4)
+	for i := 0; i < 500; i++ {
+		r := mustRect(Point{float64(i)}, []float64{1})
+		rt.Insert(&r)
+	}
+	verify(t, rt)
+	if rt.Size() != 500 {
+		t.Errorf("Size() = %d, expected 500", rt.Size())
+	}
+}
+
+func TestReinsertPercent(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	all := mustRect(Point{-1, -1}, []float64{200, 200})
+
+	for _, percent := range []int{-10, 0, 1, 10, 30, 50, 90, 100, 150} {
+		t.Run(fmt.Sprint(percent), func(t *testing.T) {
+			rt := NewTreeRStar(2, 3, 10)
+			rt.ReinsertPercent = percent
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+			verify(t, rt)
+			checkCounts(t, rt.root)
+			if rt.Size() != len(things) {
+				t.Errorf("Size() = %d, expected %d", rt.Size(), len(things))
+			}
+			q := rt.SearchIntersect(all)
+			ensureDisorderedSubset(t, q, things)
+			if len(q) != len(things) {
+				t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(things))
+			}
+
+			for _, thing := range things[:500] {
+				if !rt.Delete(thing) {
+					t.Fatalf("failed to delete %v", thing)
+				}
+			}
+			verify(t, rt)
+			if rt.Size() != len(things)-500 {
+				t.Errorf("Size() = %d after deletes, expected %d", rt.Size(), len(things)-500)
+			}
+		})
+	}
+}
+
+func BenchmarkInsertSplitStrategies(b *testing.B) {
+	things := randomRects(rand.New(rand.NewSource(1)), 10000)
+	for _, strategy := range []struct {
+		name     string
+		strategy SplitStrategy
+	}{
+		{"QuadraticSplit", QuadraticSplit},
+		{"RStarSplit", RStarSplit},
+		{"LinearSplit", LinearSplit},
+	} {
+		b.Run(strategy.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rt := NewTree(2, 25, 50)
+				rt.SplitStrategy = strategy.strategy
+				for _, thing := range things {
+					rt.Insert(thing)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkInsertAllocs reports the allocations per insert, which come
+// mostly from the nodes and entries of the tree since the bounding boxes
+// compared while choosing nodes and splitting them are scratch rectangles.
+func BenchmarkInsertAllocs(b *testing.B) {
+	things := randomRects(rand.New(rand.NewSource(1)), 10000)
+	for _, strategy := range []struct {
+		name     string
+		strategy SplitStrategy
+	}{
+		{"QuadraticSplit", QuadraticSplit},
+		{"RStarSplit", RStarSplit},
+		{"LinearSplit", LinearSplit},
+	} {
+		b.Run(strategy.name, func(b *testing.B) {
+			build := func() {
+				rt := NewTree(2, 10, 25)
+				rt.SplitStrategy = strategy.strategy
+				rt.RotateSplits = true
+				for _, thing := range things {
+					rt.Insert(thing)
+				}
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				build()
+			}
+			b.ReportMetric(testing.AllocsPerRun(1, build)/float64(len(things)), "allocs/insert")
+		})
+	}
+}
+
+// BenchmarkInsertOverlap reports the overlap of sibling nodes, as measured by
+// Stats, of trees built by inserting objects one at a time.
+func BenchmarkInsertOverlap(b *testing.B) {
+	things := randomRects(rand.New(rand.NewSource(1)), 10000)
+	for _, tc := range []struct {
+		name    string
+		newTree func() *Rtree
+	}{
+		{"QuadraticSplit", func() *Rtree { return NewTree(2, 10, 25) }},
+		{"RStarSplit", func() *Rtree {
+			rt := NewTree(2, 10, 25)
+			rt.SplitStrategy = RStarSplit
+			return rt
+		}},
+		{"RStar", func() *Rtree { return NewTreeRStar(2, 10, 25) }},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			var rt *Rtree
+			for i := 0; i < b.N; i++ {
+				rt = tc.newTree()
+				for _, thing := range things {
+					rt.Insert(thing)
+				}
+			}
+			b.ReportMetric(rt.Stats().Overlap, "overlap")
+		})
+	}
+}
+
+func TestScratchRects(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 3000)
+	for _, strategy := range []SplitStrategy{QuadraticSplit, RStarSplit, LinearSplit} {
+		for _, rt := range []*Rtree{NewTree(2, 4, 10), NewTreeRStar(2, 4, 10)} {
+			rt.SplitStrategy = strategy
+			rt.RotateSplits = true
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+			for _, thing := range things[:1000] {
+				rt.Delete(thing)
+			}
+
+			// overwrite the scratch rectangles: none of them may be stored
+			// in the tree
+			var scratch []*Rect
+			for i := 0; i < 100; i++ {
+				r := scratchRect(2)
+				for j := range r.p {
+					r.p[j], r.q[j] = math.NaN(), math.NaN()
+				}
+				scratch = append(scratch, r)
+			}
+			releaseRect(scratch...)
+
+			if err := rt.Validate(); err != nil {
+				t.Fatalf("Validate() = %v with strategy %v", err, strategy)
+			}
+			for i := 0; i < 20; i++ {
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{10, 10})
+				var expected []Spatial
+				for _, thing := range things[1000:] {
+					if intersect(thing.Bounds(), bb) {
+						expected = append(expected, thing)
+					}
+				}
+				q := rt.SearchIntersect(bb)
+				ensureDisorderedSubset(t, expected, q)
+				if len(q) != len(expected) {
+					t.Errorf("SearchIntersect(%v) returned %d objects with strategy %v, expected %d",
+						bb, len(q), strategy, len(expected))
+				}
+			}
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things1, things2 := randomRects(rnd, 5000), randomRects(rnd, 5000)
+
+	for _, tt := range []struct {
+		name  string
+		other func() *Rtree
+	}{
+		{"dynamically built", func() *Rtree {
+			rt := NewTree(2, 3, 8)
+			for _, thing := range things2 {
+				rt.Insert(thing)
+			}
+			return rt
+		}},
+		{"bulk-loaded", func() *Rtree { return NewTree(2, 3, 8, things2...) }},
+		{"other parameters", func() *Rtree { return NewTree(2, 5, 12, things2...) }},
+		{"R*-tree", func() *Rtree {
+			rt := NewTreeRStar(2, 4, 10)
+			for _, thing := range things2 {
+				rt.Insert(thing)
+			}
+			return rt
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewTree(2, 3, 8)
+			for _, thing := range things1 {
+				rt.Insert(thing)
+			}
+			other := tt.other()
+			inserted := 0
+			rt.OnMutation(func(ev MutationEvent) {
+				if ev.Kind == InsertMutation {
+					inserted++
+				}
+			})
+
+			if err := rt.Merge(other); err != nil {
+				t.Fatalf("Merge() = %v", err)
+			}
+			if err := rt.Validate(); err != nil {
+				t.Errorf("Validate() = %v after Merge", err)
+			}
+			if rt.Size() != len(things1)+len(things2) {
+				t.Errorf("Size() = %d after Merge, expected %d", rt.Size(), len(things1)+len(things2))
+			}
+			if inserted != len(things2) {
+				t.Errorf("%d insert events, expected %d", inserted, len(things2))
+			}
+			if other.Size() != len(things2) {
+				t.Errorf("Merge changed the size of the other tree to %d", other.Size())
+			}
+			verify(t, other)
+
+			all := append(append([]Spatial{}, things1...), things2...)
+			for i := 0; i < 20; i++ {
+				bb := mustRect(Point{rnd.Float64() * 100, rnd.Float64() * 100}, []float64{10, 10})
+				var expected []Spatial
+				for _, thing := range all {
+					if intersect(thing.Bounds(), bb) {
+						expected = append(expected, thing)
+					}
+				}
+				q := rt.SearchIntersect(bb)
+				ensureDisorderedSubset(t, expected, q)
+				if len(q) != len(expected) {
+					t.Errorf("SearchIntersect(%v) returned %d objects, expected %d", bb, len(q), len(expected))
+				}
+			}
+
+			// the merged objects can be deleted
+			for _, thing := range things2 {
+				if !rt.Delete(thing) {
+					t.Fatalf("failed to delete %v after Merge", thing)
+				}
+			}
+			if err := rt.Validate(); err != nil {
+				t.Errorf("Validate() = %v after deleting the merged objects", err)
+			}
+		})
+	}
+
+	rt := NewTree(2, 3, 8, things1[:10]...)
+	var dimErr *DimError
+	if err := rt.Merge(NewTree(3, 3, 8)); !errors.As(err, &dimErr) {
+		t.Errorf("Merge() = %v with a tree of another dimension, expected a *DimError", err)
+	}
+	if err := rt.Merge(NewTree(2, 3, 8)); err != nil || rt.Size() != 10 {
+		t.Errorf("Merge() = %v with an empty tree, size %d", err, rt.Size())
+	}
+	if err := rt.Merge(rt); err != nil || rt.Size() != 20 {
+		t.Errorf("Merge() = %v with itself, size %d", err, rt.Size())
+	}
+	empty := NewTree(2, 3, 8)
+	if err := empty.Merge(rt); err != nil || empty.Size() != 20 {
+		t.Errorf("Merge() = %v into an empty tree, size %d", err, empty.Size())
+	}
+	verify(t, empty)
+}
+
+func TestEqualContents(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 1000)
+	// duplicates count as many times as they are stored
+	things = append(things, things[:10]...)
+
+	bulk := NewTree(2, 3, 8, things...)
+	dynamic := NewTree(2, 4, 10)
+	shuffled := slices.Clone(things)
+	rnd.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+	for _, thing := range shuffled {
+		dynamic.Insert(thing)
+	}
+	if !bulk.EqualContents(dynamic, nil) || !dynamic.EqualContents(bulk, nil) {
+		t.Errorf("EqualContents() = false for trees built from the same objects")
+	}
+
+	// a copy of an object with the same bounds only matches with a custom eq
+	copies := NewTree(2, 3, 8)
+	for _, thing := range things {
+		r := thing.Bounds()
+		copies.Insert(&r)
+	}
+	if bulk.EqualContents(copies, nil) {
+		t.Errorf("EqualContents() = true for different objects with the same bounds")
+	}
+	sameBounds := func(a, b Spatial) bool { return a.Bounds().Equal(b.Bounds()) }
+	if !bulk.EqualContents(copies, sameBounds) {
+		t.Errorf("EqualContents() = false comparing bounds")
+	}
+
+	// a duplicate replaced by another object with the same bounds
+	dynamic.Delete(things[0])
+	other := things[0].Bounds()
+	dynamic.Insert(&other)
+	if bulk.EqualContents(dynamic, nil) {
+		t.Errorf("EqualContents() = true after replacing a duplicate")
+	}
+	dynamic.Delete(things[5])
+	if bulk.EqualContents(dynamic, nil) {
+		t.Errorf("EqualContents() = true for trees of different sizes")
+	}
+
+	if !NewTree(2, 3, 8).EqualContents(NewTree(3, 3, 8), nil) {
+		t.Errorf("EqualContents() = false for empty trees")
+	}
+}
+
+func TestGetAll(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 300)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			objs := rt.GetAll()
+			if len(objs) != rt.Size() || len(objs) != len(things) {
+				t.Fatalf("GetAll() returned %d objects, Size() = %d, expected %d", len(objs), rt.Size(), len(things))
+			}
+			ensureDisorderedSubset(t, objs, things)
+			ensureDisorderedSubset(t, things, objs)
+
+			again := rt.GetAll()
+			for i := range objs {
+				if again[i] != objs[i] {
+					t.Fatalf("GetAll() order changed at index %d", i)
+				}
+			}
+		})
+	}
+
+	if objs := NewTree(2, 3, 8).GetAll(); len(objs) != 0 {
+		t.Errorf("GetAll() on empty tree returned %v", objs)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 300)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			var nodes, objs int
+			rt.Walk(func(bb Rect, obj Spatial, leaf bool) bool {
+				if leaf {
+					if obj == nil || !bb.Equal(obj.Bounds()) {
+						t.Errorf("leaf visit with bb %v and obj %v", bb, obj)
+					}
+					objs++
+				} else {
+					if obj != nil {
+						t.Errorf("interior visit with obj %v", obj)
+					}
+					nodes++
+				}
+				return true
+			})
+			if objs != rt.Size() {
+				t.Errorf("visited %d objects, expected %d", objs, rt.Size())
+			}
+			if expected := len(rt.GetAllBoundingBoxes()); nodes != expected {
+				t.Errorf("visited %d interior entries, expected %d", nodes, expected)
+			}
+
+			// prune the subtrees in the right half of the space
+			pruned := func(bb Rect) bool { return bb.p[0] >= 50 }
+			var expected func(n *node) int
+			expected = func(n *node) int {
+				if n.leaf {
+					return len(n.entries)
+				}
+				count := 0
+				for _, e := range n.entries {
+					if !pruned(e.bounds()) {
+						count += expected(e.child)
+					}
+				}
+				return count
+			}
+			objs = 0
+			rt.Walk(func(bb Rect, obj Spatial, leaf bool) bool {
+				if leaf {
+					objs++
+				}
+				return !pruned(bb)
+			})
+			if e := expected(rt.root); objs != e || objs == rt.Size() {
+				t.Errorf("visited %d objects with pruning, expected %d out of %d", objs, e, rt.Size())
+			}
+
+			// modifying the boxes doesn't change the tree
+			original := rt.String()
+			rt.Walk(func(bb Rect, obj Spatial, leaf bool) bool {
+				bb.p[0], bb.q[0] = -1, 1e9
+				return true
+			})
+			if rt.String() != original {
+				t.Errorf("modifying the boxes given by Walk changed the tree")
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 300)
+	more := randomRects(rnd, 100)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			original := rt.String()
+			bb := mustRect(Point{10, 10}, []float64{60, 60})
+			results := rt.SearchIntersect(bb)
+
+			clone := rt.Clone()
+			if clone.String() != original {
+				t.Errorf("clone differs from the original:\n%v\n%v", clone, original)
+			}
+			for _, thing := range more {
+				clone.Insert(thing)
+			}
+			for _, thing := range things[:50] {
+				if !clone.Delete(thing) {
+					t.Errorf("failed to delete %v from the clone", thing)
+				}
+			}
+			verify(t, clone)
+
+			if rt.Size() != len(things) {
+				t.Errorf("original Size() = %d after modifying the clone, expected %d", rt.Size(), len(things))
+			}
+			if rt.String() != original {
+				t.Errorf("original changed after modifying the clone")
+			}
+			after := rt.SearchIntersect(bb)
+			if len(after) != len(results) {
+				t.Errorf("SearchIntersect() on the original returned %d objects, expected %d", len(after), len(results))
+			}
+			ensureDisorderedSubset(t, after, results)
+			verify(t, rt)
+		})
+	}
+}
+
+// depth computes the depth of the subtree n by following its first entries.
+func depth(n *node) int {
+	if n.leaf {
+		return 1
+	}
+	return 1 + depth(n.entries[0].child)
+}
+
+func TestDepthTracking(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+
+	for _, rt := range []*Rtree{NewTree(2, 2, 4), NewTreeRStar(2, 2, 4)} {
+		check := func(when string) {
+			if d := depth(rt.root); rt.Depth() != d {
+				t.Fatalf("Depth() = %d %s, recomputed %d", rt.Depth(), when, d)
+			}
+			if !rt.IsBalanced() {
+				t.Fatalf("IsBalanced() = false %s", when)
+			}
+		}
+		check("on an empty tree")
+		for i, thing := range things {
+			rt.Insert(thing)
+			check(fmt.Sprintf("after %d inserts", i+1))
+		}
+		for i, j := range rnd.Perm(len(things)) {
+			rt.Delete(things[j])
+			check(fmt.Sprintf("after %d deletes", i+1))
+		}
+		if rt.Depth() != 1 {
+			t.Errorf("Depth() = %d after deleting everything", rt.Depth())
+		}
+		rt.Insert(things[0])
+		check("after inserting into the emptied tree")
+	}
+}
+
+func TestIsBalancedUnbalanced(t *testing.T) {
+	rt := NewTree(2, 2, 4, randomRects(rand.New(rand.NewSource(1)), 100)...)
+	if !rt.IsBalanced() {
+		t.Fatalf("IsBalanced() = false on a bulk-loaded tree")
+	}
+
+	// hoist a leaf one level up
+	n := rt.root
+	for n.level > 3 {
+		n = n.entries[0].child
+	}
+	if n.level != 3 {
+		t.Fatalf("tree of depth %d is too shallow", rt.Depth())
+	}
+	n.entries[1].child = n.entries[1].child.entries[0].child
+	if rt.IsBalanced() {
+		t.Errorf("IsBalanced() = true on an unbalanced tree")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 500)
+
+	for _, rt := range []*Rtree{NewTree(2, 3, 8), NewTreeRStar(2, 3, 8)} {
+		for _, thing := range things {
+			rt.Insert(thing)
+		}
+		if err := rt.Validate(); err != nil {
+			t.Errorf("Validate() = %v on a valid tree", err)
+		}
+	}
+
+	rt := NewTree(2, 3, 8)
+	rt.Load(things...)
+	if err := rt.Validate(); err != nil {
+		t.Errorf("Validate() = %v on a loaded tree", err)
+	}
+	if err := NewTree(2, 3, 8).Validate(); err != nil {
+		t.Errorf("Validate() = %v on an empty tree", err)
+	}
+}
+
+func TestNodeCounts(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 600)
+
+	linear := NewTree(2, 3, 8)
+	linear.SplitStrategy = LinearSplit
+	linear.RotateSplits = true
+	for _, rt := range []*Rtree{NewTree(2, 3, 8), NewTreeRStar(2, 3, 8), linear} {
+		var stored []Spatial
+		for i := 0; i < 2000; i++ {
+			switch {
+			case len(stored) > 0 && rnd.Intn(3) == 0:
+				j := rnd.Intn(len(stored))
+				if !rt.Delete(stored[j]) {
+					t.Fatalf("failed to delete %v", stored[j])
+				}
+				stored = append(stored[:j], stored[j+1:]...)
+			case len(stored) < len(things):
+				thing := things[len(stored)]
+				if slices.Contains(stored, thing) {
+					continue
+				}
+				rt.Insert(thing)
+				stored = append(stored, thing)
+			}
+			if err := rt.Validate(); err != nil {
+				t.Fatalf("Validate() = %v after %d operations", err, i+1)
+			}
+			if rt.root.count != rt.Size() {
+				t.Fatalf("root counts %d objects in a tree of size %d", rt.root.count, rt.Size())
+			}
+		}
+
+		if err := rt.InsertBatch(randomRects(rnd, 100)); err != nil {
+			t.Fatalf("InsertBatch() = %v", err)
+		}
+		if err := rt.Validate(); err != nil {
+			t.Errorf("Validate() = %v after InsertBatch", err)
+		}
+		rt.Rebuild()
+		if err := rt.Validate(); err != nil {
+			t.Errorf("Validate() = %v after Rebuild", err)
+		}
+		if err := rt.Clone().Validate(); err != nil {
+			t.Errorf("Validate() = %v on a clone", err)
+		}
+	}
+}
+
+// checkCounts checks the object count of every node of the subtree n and
+// returns the number of objects it holds.
+func checkCounts(t *testing.T, n *node) int {
+	t.Helper()
+	count := len(n.entries)
+	if !n.leaf {
+		count = 0
+		for _, e := range n.entries {
+			count += checkCounts(t, e.child)
+		}
+	}
+	if n.count != count {
+		t.Fatalf("node at level %d counts %d objects, expected %d", n.level, n.count, count)
+	}
+	return count
+}
+
+func TestValidateCorrupt(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 100)
+
+	for _, tt := range []struct {
+		name    string
+		corrupt func(rt *Rtree)
+	}{
+		{"size", func(rt *Rtree) { rt.size++ }},
+		{"height", func(rt *Rtree) { rt.height++ }},
+		{"bounding box", func(rt *Rtree) {
+			rt.root.entries[0].bb = mustRect(Point{-1, -1}, []float64{1, 1})
+		}},
+		{"parent", func(rt *Rtree) { rt.root.entries[0].child.parent = nil }},
+		{"count", func(rt *Rtree) { rt.root.entries[0].child.count++ }},
+		{"underfull", func(rt *Rtree) {
+			child := rt.root.entries[0].child
+			child.entries = child.entries[:1]
+			rt.refreshEntry(&rt.root.entries[0], child)
+		}},
+		{"object", func(rt *Rtree) {
+			leaf := rt.root
+			for !leaf.leaf {
+				leaf = leaf.entries[0].child
+			}
+			leaf.entries[0].obj = nil
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := NewTree(2, 3, 8)
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+			tt.corrupt(rt)
+			if err := rt.Validate(); err == nil {
+				t.Errorf("Validate() = nil on a tree with a corrupt %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestNewTreeChecked(t *testing.T) {
+	for _, tt := range []struct {
+		dim, min, max int
+	}{
+		{0, 2, 4},
+		{-1, 2, 4},
+		{2, 0, 4},
+		{2, -3, 4},
+		{2, 3, 4},
+		{2, 3, 3},
+		{2, 2, 2},
+		{2, 1, 0},
+		{1, 1, 1},
+	} {
+		if rt, err := NewTreeChecked(tt.dim, tt.min, tt.max); err == nil || rt != nil {
+			t.Errorf("NewTreeChecked(%d, %d, %d) = %v, %v, expected an error", tt.dim, tt.min, tt.max, rt, err)
+		}
+	}
+
+	things := randomRects(rand.New(rand.NewSource(1)), 20)
+	for _, tt := range []struct {
+		dim, min, max int
+	}{
+		{1, 1, 2},
+		{2, 2, 3},
+		{2, 3, 5},
+		{3, 25, 50},
+	} {
+		rt, err := NewTreeChecked(tt.dim, tt.min, tt.max)
+		if err != nil || rt == nil {
+			t.Errorf("NewTreeChecked(%d, %d, %d) = %v, %v, expected a tree", tt.dim, tt.min, tt.max, rt, err)
+		}
+	}
+
+	rt, err := NewTreeChecked(2, 2, 4, things...)
+	if err != nil || rt.Size() != len(things) {
+		t.Errorf("NewTreeChecked with objects = %v, %v", rt, err)
+	}
+	var dimErr *DimError
+	if _, err := NewTreeChecked(3, 2, 4, things...); !errors.As(err, &dimErr) {
+		t.Errorf("NewTreeChecked with objects of another dimension = %v, expected a *DimError", err)
+	}
+}
+
+func TestClear(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 100)
+
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+			var deleted []Spatial
+			rt.OnMutation(func(ev MutationEvent) {
+				if ev.Kind == DeleteMutation {
+					deleted = append(deleted, ev.Object)
+				}
+			})
+			rt.Clear()
+			rt.OnMutation(nil)
+
+			if len(deleted) != len(things) {
+				t.Errorf("Clear reported %d deletions, expected %d", len(deleted), len(things))
+			}
+			if rt.Size() != 0 || rt.Depth() != 1 {
+				t.Errorf("Size() = %d and Depth() = %d after Clear, expected 0 and 1", rt.Size(), rt.Depth())
+			}
+			if rt.Dim != 2 || rt.MinChildren != 3 || rt.MaxChildren != 8 {
+				t.Errorf("Clear changed the configuration to %d, %d, %d", rt.Dim, rt.MinChildren, rt.MaxChildren)
+			}
+			all := mustRect(Point{-1, -1}, []float64{200, 200})
+			if q := rt.SearchIntersect(all); len(q) != 0 {
+				t.Errorf("SearchIntersect returned %d objects after Clear", len(q))
+			}
+			if obj := rt.NearestNeighbor(Point{50, 50}); obj != nil {
+				t.Errorf("NearestNeighbor returned %v after Clear", obj)
+			}
+			if err := rt.Validate(); err != nil {
+				t.Errorf("Validate() = %v after Clear", err)
+			}
+
+			// the tree can be reused
+			for _, thing := range things {
+				rt.Insert(thing)
+			}
+			verify(t, rt)
+			if q := rt.SearchIntersect(all); len(q) != len(things) {
+				t.Errorf("SearchIntersect returned %d objects, expected %d", len(q), len(things))
+			}
+		})
+	}
+}
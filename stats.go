@@ -0,0 +1,88 @@
+package rtreego
+
+import "unsafe"
+
+// TreeStats describes how well the nodes of a tree are packed.
+type TreeStats struct {
+	// Levels holds the statistics of each level of the tree, from the
+	// leaves at Levels[0] up to the root at Levels[Depth()-1].
+	Levels []LevelStats
+
+	// Overlap is the sum of the measures of the pairwise intersections of
+	// sibling nodes, which searches may have to visit together.
+	Overlap float64
+
+	// Area is the sum of the measures of the bounding boxes of all the nodes
+	// below the root.
+	Area float64
+}
+
+// LevelStats describes the nodes at one level of a tree.
+type LevelStats struct {
+	// Level is the level of the nodes, starting at 1 for the leaves.
+	Level int
+	// Nodes is the number of nodes at this level.
+	Nodes int
+	// Entries is the total number of entries in these nodes.
+	Entries int
+	// FillFactor is the average number of entries per node relative to
+	// MaxChildren, between 0 and 1 except for small trees kept in a
+	// single leaf.
+	FillFactor float64
+}
+
+// Stats walks tree and returns statistics about its nodes, which help tuning
+// MinChildren and MaxChildren and diagnosing degenerate trees.
+func (tree *Rtree) Stats() TreeStats {
+	stats := TreeStats{Levels: make([]LevelStats, tree.height)}
+	for i := range stats.Levels {
+		stats.Levels[i].Level = i + 1
+	}
+	tree.root.stats(&stats)
+	for i := range stats.Levels {
+		if l := &stats.Levels[i]; l.Nodes > 0 && tree.MaxChildren > 0 {
+			l.FillFactor = float64(l.Entries) / float64(l.Nodes*tree.MaxChildren)
+		}
+	}
+	return stats
+}
+
+func (n *node) stats(stats *TreeStats) {
+	l := &stats.Levels[n.level-1]
+	l.Nodes++
+	l.Entries += len(n.entries)
+	if n.leaf {
+		return
+	}
+	for i, e := range n.entries {
+		bb := e.bounds()
+		stats.Area += bb.Size()
+		for _, sibling := range n.entries[i+1:] {
+			stats.Overlap += overlap(bb, sibling.bounds())
+		}
+		e.child.stats(stats)
+	}
+}
+
+// MemStats returns the number of nodes and entries of tree, and an estimate of
+// the memory they occupy in bytes: the node structures, the arrays of entries
+// and the coordinates of the bounding boxes they store.  The Spatial objects
+// themselves aren't included, only the interface values referring to them, so
+// the estimate is the overhead of indexing the objects with tree.
+func (tree *Rtree) MemStats() (nodes, entries int, approxBytes int64) {
+	approxBytes = int64(unsafe.Sizeof(*tree))
+	tree.root.memStats(&nodes, &entries, &approxBytes)
+	return nodes, entries, approxBytes
+}
+
+func (n *node) memStats(nodes, entries *int, approxBytes *int64) {
+	*nodes++
+	*entries += len(n.entries)
+	*approxBytes += int64(unsafe.Sizeof(*n)) + int64(cap(n.entries))*int64(unsafe.Sizeof(entry{}))
+	for _, e := range n.entries {
+		*approxBytes += int64(cap(e.bb.p)+cap(e.bb.q)) * int64(unsafe.Sizeof(float64(0)))
+		if !n.leaf {
+			e.child.memStats(nodes, entries, approxBytes)
+		}
+	}
+}
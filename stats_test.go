@@ -0,0 +1,131 @@
+package rtreego
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 1000)
+	rt := NewTree(2, 3, 8)
+	rt.Load(things...)
+
+	stats := rt.Stats()
+	if len(stats.Levels) != rt.Depth() {
+		t.Fatalf("Stats() has %d levels in a tree of depth %d", len(stats.Levels), rt.Depth())
+	}
+	if leaves := stats.Levels[0]; leaves.Entries != len(things) {
+		t.Errorf("leaves hold %d entries, expected %d", leaves.Entries, len(things))
+	}
+	// bulk loading packs the leaves
+	if fill := stats.Levels[0].FillFactor; fill < 0.9 {
+		t.Errorf("leaves of a bulk-loaded tree have a fill factor of %v", fill)
+	}
+	if root := stats.Levels[rt.Depth()-1]; root.Nodes != 1 {
+		t.Errorf("%d nodes at the root level", root.Nodes)
+	}
+	for i, l := range stats.Levels[:rt.Depth()-1] {
+		if l.Level != i+1 {
+			t.Errorf("level %d at index %d", l.Level, i)
+		}
+		if l.FillFactor < float64(rt.MinChildren)/float64(rt.MaxChildren) || l.FillFactor > 1 {
+			t.Errorf("fill factor %v at level %d", l.FillFactor, l.Level)
+		}
+		if next := stats.Levels[i+1]; next.Entries != l.Nodes {
+			t.Errorf("%d entries at level %d pointing to %d nodes", next.Entries, next.Level, l.Nodes)
+		}
+	}
+	if stats.Area <= 0 || stats.Overlap < 0 {
+		t.Errorf("Stats() has area %v and overlap %v", stats.Area, stats.Overlap)
+	}
+
+	empty := NewTree(2, 3, 8).Stats()
+	if len(empty.Levels) != 1 || empty.Levels[0].Nodes != 1 || empty.Levels[0].Entries != 0 || empty.Area != 0 {
+		t.Errorf("Stats() = %+v on an empty tree", empty)
+	}
+}
+
+func TestMemStats(t *testing.T) {
+	things := randomRects(rand.New(rand.NewSource(1)), 1000)
+	for _, tc := range tests(2, 3, 8, things...) {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := tc.build()
+
+			// count the nodes and entries with a manual traversal
+			var nodes, entries int
+			var walk func(n *node)
+			walk = func(n *node) {
+				nodes++
+				entries += len(n.entries)
+				for _, e := range n.entries {
+					if e.child != nil {
+						walk(e.child)
+					}
+				}
+			}
+			walk(rt.root)
+
+			gotNodes, gotEntries, bytes := rt.MemStats()
+			if gotNodes != nodes || gotEntries != entries {
+				t.Errorf("MemStats() = %d nodes and %d entries, expected %d and %d", gotNodes, gotEntries, nodes, entries)
+			}
+			// at least the coordinates of the stored bounding boxes
+			if min := int64(entries * 2 * rt.Dim * 8); bytes < min {
+				t.Errorf("MemStats() estimates %d bytes, less than the %d bytes of coordinates", bytes, min)
+			}
+
+			// lazy bounds don't store the interior bounding boxes
+			rt.Rebuild()
+			_, _, bytes = rt.MemStats()
+			rt.LazyBounds = true
+			rt.Rebuild()
+			if _, _, lazyBytes := rt.MemStats(); lazyBytes >= bytes {
+				t.Errorf("MemStats() estimates %d bytes with LazyBounds, expected less than %d", lazyBytes, bytes)
+			}
+		})
+	}
+
+	if nodes, entries, _ := NewTree(2, 3, 8).MemStats(); nodes != 1 || entries != 0 {
+		t.Errorf("MemStats() = %d nodes and %d entries on an empty tree", nodes, entries)
+	}
+}
+
+func TestMemStatsChurn(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	things := randomRects(rnd, 2000)
+
+	rt := NewTree(2, 3, 8, things[:1000]...)
+	stored := append([]Spatial(nil), things[:1000]...)
+	spare := append([]Spatial(nil), things[1000:]...)
+	_, _, initial := rt.MemStats()
+	for i := 0; i < 100000; i++ {
+		// replace a random object by a spare one
+		j, k := rnd.Intn(len(stored)), rnd.Intn(len(spare))
+		if !rt.Delete(stored[j]) {
+			t.Fatalf("failed to delete %v", stored[j])
+		}
+		rt.Insert(spare[k])
+		stored[j], spare[k] = spare[k], stored[j]
+
+		if i%10000 == 0 {
+			if _, _, bytes := rt.MemStats(); bytes > 2*initial {
+				t.Fatalf("MemStats() estimates %d bytes after %d replacements, more than twice the initial %d", bytes, i, initial)
+			}
+		}
+	}
+	verify(t, rt)
+
+	// the single leaf of a tree below its LinearScanThreshold shrinks
+	rt = NewTree(2, 3, 8)
+	rt.LinearScanThreshold = len(things)
+	for _, thing := range things {
+		rt.Insert(thing)
+	}
+	_, _, full := rt.MemStats()
+	for _, thing := range things[100:] {
+		rt.Delete(thing)
+	}
+	if _, _, bytes := rt.MemStats(); bytes > full/10 {
+		t.Errorf("MemStats() estimates %d bytes for %d objects, expected much less than the %d bytes for %d", bytes, rt.Size(), full, len(things))
+	}
+}
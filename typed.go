@@ -0,0 +1,65 @@
+package rtreego
+
+// TypedTree wraps an Rtree storing objects of a single type T, so that they
+// can be inserted and queried without converting them to and from Spatial.
+//
+// The wrapped tree must only hold objects of type T.  Methods that aren't
+// wrapped can be called on the tree returned by Tree.
+type TypedTree[T Spatial] struct {
+	tree *Rtree
+}
+
+// NewTypedTree returns a TypedTree wrapping tree.
+func NewTypedTree[T Spatial](tree *Rtree) *TypedTree[T] {
+	return &TypedTree[T]{tree: tree}
+}
+
+// Tree returns the wrapped tree.
+func (tt *TypedTree[T]) Tree() *Rtree {
+	return tt.tree
+}
+
+// Size returns the number of objects currently stored in the tree.
+func (tt *TypedTree[T]) Size() int {
+	return tt.tree.Size()
+}
+
+// Insert inserts obj into the tree.
+func (tt *TypedTree[T]) Insert(obj T) error {
+	return tt.tree.Insert(obj)
+}
+
+// Delete removes obj from the tree and reports whether it was found.
+func (tt *TypedTree[T]) Delete(obj T) bool {
+	return tt.tree.Delete(obj)
+}
+
+// SearchIntersect returns all objects that intersect the specified rectangle.
+func (tt *TypedTree[T]) SearchIntersect(bb Rect, filters ...Filter) []T {
+	return typed[T](tt.tree.SearchIntersect(bb, filters...))
+}
+
+// NearestNeighbor returns the closest object to the specified point, and
+// false if the tree is empty.
+func (tt *TypedTree[T]) NearestNeighbor(p Point) (T, bool) {
+	obj := tt.tree.NearestNeighbor(p)
+	if obj == nil {
+		var zero T
+		return zero, false
+	}
+	return obj.(T), true
+}
+
+// GetAll returns all objects stored in the tree.
+func (tt *TypedTree[T]) GetAll() []T {
+	return typed[T](tt.tree.GetAll())
+}
+
+// typed converts objs to a slice of T.
+func typed[T Spatial](objs []Spatial) []T {
+	result := make([]T, len(objs))
+	for i, obj := range objs {
+		result[i] = obj.(T)
+	}
+	return result
+}
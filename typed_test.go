@@ -0,0 +1,62 @@
+package rtreego
+
+import (
+	"math/rand"
+	"testing"
+)
+
+type city struct {
+	name     string
+	location Point
+}
+
+func (c *city) Bounds() Rect {
+	return c.location.ToRect(0)
+}
+
+func TestTypedTree(t *testing.T) {
+	tt := NewTypedTree[*city](NewTree(2, 3, 6))
+	if _, ok := tt.NearestNeighbor(Point{0, 0}); ok {
+		t.Errorf("NearestNeighbor() found an object in an empty tree")
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	var cities []*city
+	for i := 0; i < 50; i++ {
+		c := &city{string(rune('a' + i%26)), Point{rnd.Float64() * 100, rnd.Float64() * 100}}
+		cities = append(cities, c)
+		if err := tt.Insert(c); err != nil {
+			t.Fatalf("Insert() = %v", err)
+		}
+	}
+	if tt.Size() != len(cities) {
+		t.Errorf("Size() = %d, expected %d", tt.Size(), len(cities))
+	}
+
+	// results are typed, so fields are accessible without assertions
+	nearest, ok := tt.NearestNeighbor(cities[7].location)
+	if !ok || nearest != cities[7] || nearest.name != cities[7].name {
+		t.Errorf("NearestNeighbor() = %v, %v, expected %v", nearest, ok, cities[7])
+	}
+
+	bb := cities[3].location.ToRect(10)
+	for _, c := range tt.SearchIntersect(bb) {
+		if !intersect(bb, c.location.ToRect(0)) {
+			t.Errorf("SearchIntersect(%v) returned %s at %v", bb, c.name, c.location)
+		}
+	}
+
+	if !tt.Delete(cities[0]) {
+		t.Errorf("failed to delete %v", cities[0])
+	}
+	all := tt.GetAll()
+	if len(all) != len(cities)-1 {
+		t.Errorf("GetAll() returned %d objects, expected %d", len(all), len(cities)-1)
+	}
+	for _, c := range all {
+		if c == cities[0] {
+			t.Errorf("GetAll() returned the deleted object")
+		}
+	}
+	verify(t, tt.Tree())
+}